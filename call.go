@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Call types reported in the grpc context of captured events.
+const (
+	callTypeUnary        = "unary"
+	callTypeClientStream = "client_stream"
+	callTypeServerStream = "server_stream"
+	callTypeBidiStream   = "bidi_stream"
+)
+
+// requestHeaders lists the metadata keys copied to the headers of the request attached to captured events when
+// CaptureMetadata is disabled.
+var requestHeaders = []string{"content-type", "user-agent", "grpc-accept-encoding"}
+
+// callDetails describes an RPC for the grpc context and the request of the events captured while it runs.
+type callDetails struct {
+	fullMethod     string
+	callType       string
+	deadline       time.Time
+	authority      string
+	target         string
+	contentSubtype string
+	compression    string
+	headers        map[string]string
+
+	// peer is read when an event is captured, since client calls only learn their peer once the call completes.
+	peer func() *peer.Peer
+}
+
+// callTypeOf returns the call type of an RPC based on which sides of it stream messages.
+func callTypeOf(clientStreams, serverStreams bool) string {
+	switch {
+	case clientStreams && serverStreams:
+		return callTypeBidiStream
+	case clientStreams:
+		return callTypeClientStream
+	case serverStreams:
+		return callTypeServerStream
+	default:
+		return callTypeUnary
+	}
+}
+
+// splitMethod splits a full method name of the form "/package.Service/Method" into its service and method.
+func splitMethod(fullMethod string) (string, string) {
+	name := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return "", name
+}
+
+// contentSubtypeOf returns the content-subtype of a gRPC content-type, which is "proto" when none is given.
+func contentSubtypeOf(contentType string) string {
+	subtype, ok := strings.CutPrefix(contentType, "application/grpc")
+	if !ok {
+		return ""
+	}
+	if subtype == "" || subtype[0] != '+' && subtype[0] != ';' {
+		return "proto"
+	}
+	return strings.ToLower(subtype[1:])
+}
+
+// firstValue returns the first value of a metadata key, if any.
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// compressedStream is the part of the transport stream of a server call reporting the compression of the request.
+// grpc consumes the grpc-encoding header, so it never shows up in the incoming metadata.
+type compressedStream interface {
+	RecvCompress() string
+}
+
+// serverCallDetails describes an RPC handled by a server interceptor.
+func serverCallDetails(ctx context.Context, fullMethod, callType string, o *options) *callDetails {
+	md, _ := metadata.FromIncomingContext(ctx)
+	d := &callDetails{
+		fullMethod:     fullMethod,
+		callType:       callType,
+		authority:      firstValue(md, ":authority"),
+		contentSubtype: contentSubtypeOf(firstValue(md, "content-type")),
+		headers:        newMetadataFilter(o).filter(md),
+		peer: func() *peer.Peer {
+			p, _ := peer.FromContext(ctx)
+			return p
+		},
+	}
+	d.deadline, _ = ctx.Deadline()
+	if stream, ok := grpc.ServerTransportStreamFromContext(ctx).(compressedStream); ok {
+		d.compression = stream.RecvCompress()
+	}
+	return d
+}
+
+// clientCallDetails describes an RPC sent by a client interceptor. The peer is the one the interceptor asked grpc to
+// fill in with grpc.Peer, which is only known once the call completes, and the metadata is the one of the caller,
+// without the trace headers added by the interceptor.
+func clientCallDetails(ctx context.Context,
+	cc *grpc.ClientConn,
+	md metadata.MD,
+	fullMethod, callType string,
+	callOpts []grpc.CallOption,
+	p *peer.Peer,
+	o *options) *callDetails {
+
+	d := &callDetails{
+		fullMethod:     fullMethod,
+		callType:       callType,
+		contentSubtype: "proto",
+		headers:        newMetadataFilter(o).filter(md),
+		peer:           func() *peer.Peer { return p },
+	}
+	d.deadline, _ = ctx.Deadline()
+	if cc != nil {
+		d.target = cc.Target()
+	}
+	for _, opt := range callOpts {
+		switch opt := opt.(type) {
+		case grpc.ContentSubtypeCallOption:
+			d.contentSubtype = strings.ToLower(opt.ContentSubtype)
+		case grpc.CompressorCallOption:
+			d.compression = opt.CompressorType
+		case grpc.AuthorityOverrideCallOption:
+			d.authority = opt.Authority
+		}
+	}
+	return d
+}
+
+// context builds the grpc context of an event captured for the given hint.
+func (d *callDetails) context(hint *sentry.EventHint) sentry.Context {
+	service, method := splitMethod(d.fullMethod)
+	c := sentry.Context{
+		"service":   service,
+		"method":    method,
+		"call_type": d.callType,
+	}
+	if hint != nil && hint.OriginalException != nil {
+		s := status.Convert(hint.OriginalException)
+		c["status_code"] = s.Code().String()
+		c["status_message"] = s.Message()
+	}
+	if !d.deadline.IsZero() {
+		c["deadline"] = d.deadline.UTC().Format(time.RFC3339Nano)
+	}
+	if p := d.peer(); p != nil && p.Addr != nil {
+		c["peer_address"] = p.Addr.String()
+	}
+
+	optional := map[string]string{
+		"authority":       d.authority,
+		"target":          d.target,
+		"content_subtype": d.contentSubtype,
+		"compression":     d.compression,
+	}
+	for k, v := range optional {
+		if v != "" {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+// request builds the request of an event, using the full method as URL and the allowed metadata as headers.
+func (d *callDetails) request() *sentry.Request {
+	return &sentry.Request{URL: d.fullMethod, Method: "POST", Headers: d.headers}
+}
+
+// attachCallDetails adds the grpc context and the request to the events captured during the call.
+func attachCallDetails(events *callEvents, d *callDetails) {
+	events.addEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if event.Type == "transaction" {
+			return event
+		}
+		if event.Contexts == nil {
+			event.Contexts = make(map[string]sentry.Context)
+		}
+		event.Contexts["grpc"] = d.context(hint)
+		// A request already on the event describes another call, e.g. the HTTP request that led to this one, so none
+		// of it is kept. The body of this call is attached by a later processor.
+		event.Request = d.request()
+		return event
+	})
+}
@@ -0,0 +1,221 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestCallTypeOf(t *testing.T) {
+	tests := []struct {
+		clientStreams bool
+		serverStreams bool
+		expected      string
+	}{
+		{false, false, callTypeUnary},
+		{true, false, callTypeClientStream},
+		{false, true, callTypeServerStream},
+		{true, true, callTypeBidiStream},
+	}
+
+	for _, tt := range tests {
+		if got := callTypeOf(tt.clientStreams, tt.serverStreams); got != tt.expected {
+			t.Errorf("Expected %s for client=%v server=%v, got %s", tt.expected, tt.clientStreams, tt.serverStreams, got)
+		}
+	}
+}
+
+func TestSplitMethod(t *testing.T) {
+	tests := []struct {
+		fullMethod string
+		service    string
+		method     string
+	}{
+		{"/test.v1.Service/Method", "test.v1.Service", "Method"},
+		{"/Service/Method", "Service", "Method"},
+		{"Method", "", "Method"},
+	}
+
+	for _, tt := range tests {
+		service, method := splitMethod(tt.fullMethod)
+		if service != tt.service || method != tt.method {
+			t.Errorf("Expected %s and %s for %s, got %s and %s", tt.service, tt.method, tt.fullMethod, service, method)
+		}
+	}
+}
+
+func TestContentSubtypeOf(t *testing.T) {
+	tests := map[string]string{
+		"application/grpc":           "proto",
+		"application/grpc+json":      "json",
+		"application/grpc+Proto":     "proto",
+		"application/grpc;codec=xml": "codec=xml",
+		"application/json":           "",
+		"":                           "",
+	}
+
+	for contentType, expected := range tests {
+		if got := contentSubtypeOf(contentType); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, contentType, got)
+		}
+	}
+}
+
+// mockTransportStream is the transport stream of a server call received with the given compression
+type mockTransportStream struct {
+	compression string
+}
+
+func (m *mockTransportStream) Method() string               { return "/test.v1.Service/Method" }
+func (m *mockTransportStream) SetHeader(metadata.MD) error  { return nil }
+func (m *mockTransportStream) SendHeader(metadata.MD) error { return nil }
+func (m *mockTransportStream) SetTrailer(metadata.MD) error { return nil }
+func (m *mockTransportStream) RecvCompress() string         { return m.compression }
+
+func TestUnaryServerInterceptor_GrpcContext(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	deadline := time.Now().Add(time.Minute)
+	ctx, cancel := context.WithDeadline(sentry.SetHubOnContext(context.Background(), hub), deadline)
+	defer cancel()
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(
+		":authority", "api.example.com",
+		"content-type", "application/grpc+json",
+		"user-agent", "grpc-go/1.73.0",
+		"authorization", "Bearer secret",
+	))
+	ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 4242}})
+	ctx = grpc.NewContextWithServerTransportStream(ctx, &mockTransportStream{compression: "gzip"})
+
+	interceptor := UnaryServerInterceptor()
+	handler := &mockUnaryHandler{err: status.Error(codes.NotFound, "account not found")}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.v1.Service/Method"}
+	_, _ = interceptor(ctx, "request", info, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	expected := map[string]interface{}{
+		"service":         "test.v1.Service",
+		"method":          "Method",
+		"call_type":       callTypeUnary,
+		"status_code":     "NotFound",
+		"status_message":  "account not found",
+		"deadline":        deadline.UTC().Format(time.RFC3339Nano),
+		"peer_address":    "10.0.0.1:4242",
+		"authority":       "api.example.com",
+		"content_subtype": "json",
+		"compression":     "gzip",
+	}
+	grpcContext := events[0].Contexts["grpc"]
+	for k, v := range expected {
+		if grpcContext[k] != v {
+			t.Errorf("Expected grpc context %s to be %v, got %v", k, v, grpcContext[k])
+		}
+	}
+
+	request := events[0].Request
+	if request == nil || request.URL != "/test.v1.Service/Method" {
+		t.Fatalf("Expected the request URL to be the full method, got %+v", request)
+	}
+	if request.Headers["user-agent"] != "grpc-go/1.73.0" {
+		t.Errorf("Expected user-agent header, got %v", request.Headers)
+	}
+	if _, ok := request.Headers["authorization"]; ok {
+		t.Errorf("Expected authorization not to be a header, got %v", request.Headers)
+	}
+	if request.Data == "" {
+		t.Error("Expected the request body to be kept alongside the call details")
+	}
+
+	if transactions := transport.Transactions(); len(transactions) != 1 || transactions[0].Contexts["grpc"] != nil {
+		t.Error("Expected the grpc context not to be attached to the transaction")
+	}
+}
+
+func TestStreamServerInterceptor_GrpcContext(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := StreamServerInterceptor()
+	handler := &mockStreamHandler{err: status.Error(codes.Internal, "stream failed")}
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Chat", IsClientStream: true, IsServerStream: true}
+	_ = interceptor(nil, &mockServerStream{ctx: ctx}, info, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if got := events[0].Contexts["grpc"]["call_type"]; got != callTypeBidiStream {
+		t.Errorf("Expected call type %s, got %v", callTypeBidiStream, got)
+	}
+	if got := events[0].Contexts["grpc"]["status_code"]; got != "Internal" {
+		t.Errorf("Expected status code Internal, got %v", got)
+	}
+}
+
+func TestAttachCallDetails_ReplacesRequest(t *testing.T) {
+	events := &callEvents{}
+	attachCallDetails(events, &callDetails{
+		fullMethod: "/down.Service/Method",
+		callType:   callTypeUnary,
+		peer:       func() *peer.Peer { return nil },
+	})
+
+	event := &sentry.Event{Request: &sentry.Request{URL: "/up.Service/Method", Data: "upstream request"}}
+	event = events.process(event, nil)
+	if event.Request.URL != "/down.Service/Method" || event.Request.Data != "" {
+		t.Errorf("Expected the request of another call to be replaced, got %+v", event.Request)
+	}
+}
+
+func TestUnaryClientInterceptor_GrpcContext(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	ctx = metadata.AppendToOutgoingContext(ctx, "user-agent", "billing-client")
+
+	interceptor := UnaryClientInterceptor()
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		// The peer is filled in by grpc once the call has completed.
+		for _, opt := range opts {
+			if opt, ok := opt.(grpc.PeerCallOption); ok {
+				opt.PeerAddr.Addr = &net.TCPAddr{IP: net.IPv4(10, 0, 0, 2), Port: 443}
+			}
+		}
+		return status.Error(codes.Unavailable, "connection refused")
+	}
+	_ = interceptor(ctx, "/test.Service/Method", "request", nil, nil, invoker,
+		grpc.UseCompressor("gzip"), grpc.CallContentSubtype("json"))
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	expected := map[string]interface{}{
+		"service":         "test.Service",
+		"method":          "Method",
+		"call_type":       callTypeUnary,
+		"status_code":     "Unavailable",
+		"peer_address":    "10.0.0.2:443",
+		"content_subtype": "json",
+		"compression":     "gzip",
+	}
+	grpcContext := events[0].Contexts["grpc"]
+	for k, v := range expected {
+		if grpcContext[k] != v {
+			t.Errorf("Expected grpc context %s to be %v, got %v", k, v, grpcContext[k])
+		}
+	}
+	if events[0].Request == nil || events[0].Request.Headers["user-agent"] != "billing-client" {
+		t.Errorf("Expected the outgoing user-agent as request header, got %+v", events[0].Request)
+	}
+}
@@ -0,0 +1,136 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// LevelFunc returns the Sentry level of an error reported with the given status code. An empty level falls back to
+// the configured table.
+type LevelFunc func(code codes.Code) sentry.Level
+
+// defaultLevels maps status codes to the level of the events reporting them. Codes that usually stem from the
+// caller are reported as warnings or info, those denoting a failure of the service as errors, and data loss as
+// fatal. Codes missing from the table are reported as errors.
+var defaultLevels = map[codes.Code]sentry.Level{
+	codes.Canceled:           sentry.LevelInfo,
+	codes.Unknown:            sentry.LevelError,
+	codes.InvalidArgument:    sentry.LevelWarning,
+	codes.DeadlineExceeded:   sentry.LevelWarning,
+	codes.NotFound:           sentry.LevelInfo,
+	codes.AlreadyExists:      sentry.LevelWarning,
+	codes.PermissionDenied:   sentry.LevelWarning,
+	codes.ResourceExhausted:  sentry.LevelWarning,
+	codes.FailedPrecondition: sentry.LevelWarning,
+	codes.Aborted:            sentry.LevelWarning,
+	codes.OutOfRange:         sentry.LevelWarning,
+	codes.Unimplemented:      sentry.LevelError,
+	codes.Internal:           sentry.LevelError,
+	codes.Unavailable:        sentry.LevelError,
+	codes.DataLoss:           sentry.LevelFatal,
+	codes.Unauthenticated:    sentry.LevelWarning,
+}
+
+// levelFor returns the level of an event reporting an error with the given status code.
+func (c *options) levelFor(code codes.Code) sentry.Level {
+	if c.LevelFunc != nil {
+		if level := c.LevelFunc(code); level != "" {
+			return level
+		}
+	}
+	if level, ok := c.Levels[code]; ok {
+		return level
+	}
+	return sentry.LevelError
+}
+
+// Fingerprinter returns the fingerprint grouping the events that report an error of the given method into issues.
+// An empty fingerprint leaves the grouping to Sentry.
+type Fingerprinter func(fullMethod string, err error) []string
+
+// Patterns of the dynamic parts of error messages, replaced by placeholders when fingerprinting. They are applied in
+// order, so that the digits of UUIDs and hexadecimal identifiers are not replaced as numbers on their own.
+var (
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	hexPattern    = regexp.MustCompile(`(?i)\b(?:0x[0-9a-f]+|[0-9a-f]{8,})\b`)
+	numberPattern = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+)
+
+// DefaultFingerprint groups errors by full method, status code and message, once the identifiers and numbers found
+// in the message are replaced by placeholders, so that "user 123 not found" and "user 456 not found" end up in the
+// same issue.
+func DefaultFingerprint(fullMethod string, err error) []string {
+	s := status.Convert(err)
+	return []string{fullMethod, s.Code().String(), normalizeMessage(s.Message())}
+}
+
+// normalizeMessage replaces UUIDs, hexadecimal identifiers and numbers in an error message by placeholders.
+func normalizeMessage(message string) string {
+	message = uuidPattern.ReplaceAllString(message, "<uuid>")
+	message = hexPattern.ReplaceAllStringFunc(message, func(id string) string {
+		// Long runs of digits are numbers, and long runs of letters are words.
+		if strings.HasPrefix(strings.ToLower(id), "0x") ||
+			strings.ContainsAny(id, "0123456789") && strings.ContainsAny(strings.ToLower(id), "abcdef") {
+			return "<hex>"
+		}
+		return id
+	})
+	return numberPattern.ReplaceAllString(message, "<n>")
+}
+
+// BeforeCapture is called right before an error of an RPC is captured, with the scope the event is built from. It
+// may enrich the scope, and returns false to drop the event.
+type BeforeCapture func(ctx context.Context, scope *sentry.Scope, fullMethod string, err error) bool
+
+// captureError reports an error of an RPC through the hub of the call. The event is shaped in a scope of its own, so
+// that several errors reported during the same call do not affect one another.
+func captureError(ctx context.Context, hub *sentry.Hub, o *options, fullMethod string, err error) *sentry.EventID {
+	return capture(ctx, hub, o, fullMethod, err, o.levelFor(status.Code(err)), func() *sentry.EventID {
+		return hub.CaptureException(err)
+	})
+}
+
+// capturePanic reports a value recovered from a panic of an RPC like captureError reports errors. Panics carry no
+// status code and are reported at the fatal level; the Fingerprinter and BeforeCapture get the value as an error.
+func capturePanic(ctx context.Context, hub *sentry.Hub, o *options, fullMethod string, p interface{}) *sentry.EventID {
+	err, ok := p.(error)
+	if !ok {
+		err = fmt.Errorf("%v", p)
+	}
+	return capture(ctx, hub, o, fullMethod, err, sentry.LevelFatal, func() *sentry.EventID {
+		return hub.RecoverWithContext(ctx, p)
+	})
+}
+
+// capture shapes the scope of an event reporting err at the given level, then sends it with send unless the
+// configured BeforeCapture drops it.
+func capture(ctx context.Context,
+	hub *sentry.Hub,
+	o *options,
+	fullMethod string,
+	err error,
+	level sentry.Level,
+	send func() *sentry.EventID) *sentry.EventID {
+
+	var eventID *sentry.EventID
+	hub.WithScope(func(scope *sentry.Scope) {
+		scope.SetLevel(level)
+		if o.Fingerprinter != nil {
+			if fingerprint := o.Fingerprinter(fullMethod, err); len(fingerprint) > 0 {
+				scope.SetFingerprint(fingerprint)
+			}
+		}
+		if o.BeforeCapture != nil && !o.BeforeCapture(ctx, scope, fullMethod, err) {
+			return
+		}
+		eventID = send()
+	})
+	return eventID
+}
@@ -0,0 +1,408 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestOptions_LevelFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		code     codes.Code
+		expected sentry.Level
+	}{
+		{"not found is info", nil, codes.NotFound, sentry.LevelInfo},
+		{"invalid argument is a warning", nil, codes.InvalidArgument, sentry.LevelWarning},
+		{"internal is an error", nil, codes.Internal, sentry.LevelError},
+		{"data loss is fatal", nil, codes.DataLoss, sentry.LevelFatal},
+		{"unknown codes are errors", nil, codes.Code(42), sentry.LevelError},
+		{
+			name:     "table override",
+			options:  []Option{WithLevels(map[codes.Code]sentry.Level{codes.NotFound: sentry.LevelDebug})},
+			code:     codes.NotFound,
+			expected: sentry.LevelDebug,
+		},
+		{
+			name:     "table override keeps other defaults",
+			options:  []Option{WithLevels(map[codes.Code]sentry.Level{codes.NotFound: sentry.LevelDebug})},
+			code:     codes.InvalidArgument,
+			expected: sentry.LevelWarning,
+		},
+		{
+			name: "func takes precedence",
+			options: []Option{
+				WithLevels(map[codes.Code]sentry.Level{codes.NotFound: sentry.LevelDebug}),
+				WithLevelFunc(func(code codes.Code) sentry.Level { return sentry.LevelFatal }),
+			},
+			code:     codes.NotFound,
+			expected: sentry.LevelFatal,
+		},
+		{
+			name:     "func falls back to the table",
+			options:  []Option{WithLevelFunc(func(code codes.Code) sentry.Level { return "" })},
+			code:     codes.NotFound,
+			expected: sentry.LevelInfo,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newConfig(tt.options).levelFor(tt.code); got != tt.expected {
+				t.Errorf("Expected level %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithLevels_DoesNotModifyDefaults(t *testing.T) {
+	_ = newConfig([]Option{WithLevels(map[codes.Code]sentry.Level{codes.NotFound: sentry.LevelDebug})})
+	if defaultLevels[codes.NotFound] != sentry.LevelInfo {
+		t.Errorf("Expected the default levels to be left unchanged, got %s", defaultLevels[codes.NotFound])
+	}
+}
+
+func TestInterceptors_Level(t *testing.T) {
+	t.Run("unary server", func(t *testing.T) {
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		handler := &mockUnaryHandler{err: status.Error(codes.NotFound, "missing")}
+		_, _ = UnaryServerInterceptor()(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+		assertEventLevel(t, transport, sentry.LevelInfo)
+	})
+
+	t.Run("stream server", func(t *testing.T) {
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		handler := &mockStreamHandler{err: status.Error(codes.DataLoss, "corrupted")}
+		_ = StreamServerInterceptor()(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, handler.handle)
+		assertEventLevel(t, transport, sentry.LevelFatal)
+	})
+
+	t.Run("unary client", func(t *testing.T) {
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		invoker := &mockUnaryInvoker{err: status.Error(codes.InvalidArgument, "bad")}
+		_ = UnaryClientInterceptor()(ctx, "/test.Service/Method", "request", nil, nil, invoker.invoke)
+		assertEventLevel(t, transport, sentry.LevelWarning)
+	})
+
+	t.Run("stream client", func(t *testing.T) {
+		cs := &scriptedClientStream{recvErrs: []error{status.Error(codes.Unavailable, "gone")}}
+		stream, transport := startClientStream(t, context.Background(), &grpc.StreamDesc{ServerStreams: true}, cs,
+			WithLevels(map[codes.Code]sentry.Level{codes.Unavailable: sentry.LevelWarning}))
+		_ = stream.RecvMsg(nil)
+		assertEventLevel(t, transport, sentry.LevelWarning)
+	})
+}
+
+func TestUnaryServerInterceptor_PanicLevel(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	handler := &mockUnaryHandler{panic: true}
+	_, _ = UnaryServerInterceptor()(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+	assertEventLevel(t, transport, sentry.LevelFatal)
+}
+
+func assertEventLevel(t *testing.T, transport *recordingTransport, expected sentry.Level) {
+	t.Helper()
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].Level != expected {
+		t.Errorf("Expected level %s, got %s", expected, events[0].Level)
+	}
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	tests := map[string]string{
+		"user 123 not found": "user <n> not found",
+		"account 8f14e45f-ceea-467a-9af0-4a1b2c3d4e5f is locked": "account <uuid> is locked",
+		"object 5f2b9c0e1a7d is missing":                         "object <hex> is missing",
+		"pointer 0xc000123abc":                                   "pointer <hex>",
+		"took 1.5 seconds, limit is 1":                           "took <n> seconds, limit is <n>",
+		"order 12345678 has been cancelled":                      "order <n> has been cancelled",
+		"deadbeef and v2 stay":                                   "deadbeef and v2 stay",
+		"connection refused":                                     "connection refused",
+	}
+
+	for message, expected := range tests {
+		if got := normalizeMessage(message); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, message, got)
+		}
+	}
+}
+
+func TestInterceptors_Fingerprint(t *testing.T) {
+	t.Run("default groups by method, code and normalized message", func(t *testing.T) {
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		interceptor := UnaryServerInterceptor()
+		info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+		for _, id := range []string{"123", "456"} {
+			handler := &mockUnaryHandler{err: status.Error(codes.NotFound, "user "+id+" not found")}
+			_, _ = interceptor(ctx, "request", info, handler.handle)
+		}
+
+		events := transport.Errors()
+		if len(events) != 2 {
+			t.Fatalf("Expected 2 events, got %d", len(events))
+		}
+		expected := []string{"/test.Service/Method", "NotFound", "user <n> not found"}
+		for _, event := range events {
+			if len(event.Fingerprint) != len(expected) {
+				t.Fatalf("Expected fingerprint %v, got %v", expected, event.Fingerprint)
+			}
+			for i := range expected {
+				if event.Fingerprint[i] != expected[i] {
+					t.Errorf("Expected fingerprint %v, got %v", expected, event.Fingerprint)
+				}
+			}
+		}
+	})
+
+	t.Run("override", func(t *testing.T) {
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		invoker := &mockUnaryInvoker{err: status.Error(codes.Unavailable, "gone")}
+		interceptor := UnaryClientInterceptor(WithFingerprinter(func(method string, err error) []string {
+			return []string{"downstream", method}
+		}))
+		_ = interceptor(ctx, "/test.Service/Method", "request", nil, nil, invoker.invoke)
+
+		events := transport.Errors()
+		if len(events) != 1 || len(events[0].Fingerprint) != 2 || events[0].Fingerprint[0] != "downstream" {
+			t.Errorf("Expected the custom fingerprint, got %v", events)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		cs := &scriptedClientStream{recvErrs: []error{status.Error(codes.Unavailable, "gone")}}
+		stream, transport := startClientStream(t, context.Background(), &grpc.StreamDesc{ServerStreams: true}, cs, WithFingerprinter(nil))
+		_ = stream.RecvMsg(nil)
+
+		events := transport.Errors()
+		if len(events) != 1 || len(events[0].Fingerprint) != 0 {
+			t.Errorf("Expected no fingerprint, got %v", events)
+		}
+	})
+}
+
+func TestInterceptors_BeforeCapture(t *testing.T) {
+	type captured struct {
+		method string
+		code   codes.Code
+	}
+	var calls []captured
+	hook := func(ctx context.Context, scope *sentry.Scope, method string, err error) bool {
+		calls = append(calls, captured{method: method, code: status.Code(err)})
+		scope.SetTag("team", "payments")
+		return status.Code(err) != codes.NotFound
+	}
+
+	t.Run("unary server enriches the event", func(t *testing.T) {
+		calls = nil
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		handler := &mockUnaryHandler{err: status.Error(codes.Internal, "boom")}
+		_, _ = UnaryServerInterceptor(WithBeforeCapture(hook))(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+		events := transport.Errors()
+		if len(events) != 1 || events[0].Tags["team"] != "payments" {
+			t.Errorf("Expected 1 event with the tag set by the hook, got %v", events)
+		}
+		if len(calls) != 1 || calls[0].method != "/test.Service/Method" || calls[0].code != codes.Internal {
+			t.Errorf("Expected the hook to see the method and error, got %v", calls)
+		}
+	})
+
+	t.Run("stream server drops the event", func(t *testing.T) {
+		calls = nil
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		handler := &mockStreamHandler{err: status.Error(codes.NotFound, "missing")}
+		_ = StreamServerInterceptor(WithBeforeCapture(hook))(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, handler.handle)
+
+		if len(transport.Errors()) != 0 || len(calls) != 1 {
+			t.Errorf("Expected the event to be dropped by the hook, got %d events", len(transport.Errors()))
+		}
+		if len(transport.Transactions()) != 1 {
+			t.Error("Expected the transaction to be kept")
+		}
+	})
+
+	t.Run("unary server enriches the panic", func(t *testing.T) {
+		calls = nil
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		handler := &mockUnaryHandler{panic: true}
+		_, _ = UnaryServerInterceptor(WithBeforeCapture(hook))(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+		events := transport.Errors()
+		if len(events) != 1 || events[0].Tags["team"] != "payments" {
+			t.Fatalf("Expected 1 event with the tag set by the hook, got %v", events)
+		}
+		if events[0].Level != sentry.LevelFatal {
+			t.Errorf("Expected panic to be reported as fatal, got %s", events[0].Level)
+		}
+		if fp := events[0].Fingerprint; len(fp) != 3 || fp[0] != "/test.Service/Method" || fp[2] != "test panic" {
+			t.Errorf("Expected panic to be fingerprinted, got %v", fp)
+		}
+		if len(calls) != 1 || calls[0].method != "/test.Service/Method" {
+			t.Errorf("Expected the hook to see the panic, got %v", calls)
+		}
+	})
+
+	t.Run("stream server drops the panic", func(t *testing.T) {
+		calls = nil
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		handler := &mockStreamHandler{panic: true}
+		drop := func(context.Context, *sentry.Scope, string, error) bool { return false }
+		err := StreamServerInterceptor(WithBeforeCapture(drop))(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, handler.handle)
+
+		if len(transport.Errors()) != 0 {
+			t.Errorf("Expected the panic to be dropped by the hook, got %d events", len(transport.Errors()))
+		}
+		if status.Code(err) != codes.Internal {
+			t.Errorf("Expected the panic to be returned as an internal error, got %v", err)
+		}
+	})
+
+	t.Run("unary client drops the event", func(t *testing.T) {
+		calls = nil
+		hub, transport := newRecordingHub(t)
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		invoker := &mockUnaryInvoker{err: status.Error(codes.NotFound, "missing")}
+		_ = UnaryClientInterceptor(WithBeforeCapture(hook))(ctx, "/test.Service/Method", "request", nil, nil, invoker.invoke)
+
+		if len(transport.Errors()) != 0 || len(calls) != 1 {
+			t.Errorf("Expected the event to be dropped by the hook, got %d events", len(transport.Errors()))
+		}
+	})
+
+	t.Run("stream client enriches the event", func(t *testing.T) {
+		calls = nil
+		cs := &scriptedClientStream{recvErrs: []error{status.Error(codes.Unavailable, "gone")}}
+		stream, transport := startClientStream(t, context.Background(), &grpc.StreamDesc{ServerStreams: true}, cs, WithBeforeCapture(hook))
+		_ = stream.RecvMsg(nil)
+
+		events := transport.Errors()
+		if len(events) != 1 || events[0].Tags["team"] != "payments" {
+			t.Errorf("Expected 1 event with the tag set by the hook, got %v", events)
+		}
+	})
+}
+
+func TestBeforeCapture_ScopeIsDiscarded(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	first := true
+	hook := func(ctx context.Context, scope *sentry.Scope, method string, err error) bool {
+		if first {
+			scope.SetTag("first", "true")
+			first = false
+		}
+		return true
+	}
+
+	cs := &failingServerStream{mockServerStream: mockServerStream{ctx: ctx}, err: status.Error(codes.DataLoss, "corrupted")}
+	interceptor := StreamServerInterceptor(WithCaptureStreamErrors(true), WithBeforeCapture(hook))
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		_ = stream.RecvMsg(nil)
+		return status.Error(codes.Internal, "boom")
+	}
+	_ = interceptor(nil, cs, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, handler)
+
+	events := transport.Errors()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	if events[0].Tags["first"] != "true" || events[1].Tags["first"] != "" {
+		t.Errorf("Expected the scope changes of the hook to apply to a single event, got %v and %v", events[0].Tags, events[1].Tags)
+	}
+}
+
+func TestInterceptors_EventProcessors(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	processor := func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if event.Extra == nil {
+			event.Extra = make(map[string]interface{})
+		}
+		event.Extra["processed"] = true
+		return event
+	}
+
+	interceptor := UnaryServerInterceptor(WithEventProcessors(processor))
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		sentry.GetHubFromContext(ctx).CaptureMessage("from the handler")
+		return nil, status.Error(codes.Internal, "boom")
+	}
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler)
+	hub.CaptureMessage("outside of any RPC")
+
+	events := transport.Errors()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	for _, event := range events {
+		processed := event.Extra["processed"] == true
+		outside := event.Message == "outside of any RPC"
+		if processed == outside {
+			t.Errorf("Expected only events captured during the RPC to be processed, got %q processed: %v", event.Message, processed)
+		}
+	}
+	if transactions := transport.Transactions(); len(transactions) != 1 || transactions[0].Extra["processed"] != true {
+		t.Error("Expected the transaction of the RPC to be processed")
+	}
+}
+
+func TestInterceptors_NestedEventProcessors(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	counting := func(name string) sentry.EventProcessor {
+		return func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			if event.Extra == nil {
+				event.Extra = make(map[string]interface{})
+			}
+			runs, _ := event.Extra[name].(int)
+			event.Extra[name] = runs + 1
+			return event
+		}
+	}
+
+	server := UnaryServerInterceptor(WithEventProcessors(counting("server")))
+	client := UnaryClientInterceptor(WithEventProcessors(counting("client")))
+	plainClient := UnaryClientInterceptor()
+	downstream := &mockUnaryInvoker{err: status.Error(codes.Unavailable, "unavailable")}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		_ = plainClient(ctx, "/plain.Service/Method", "request", nil, nil, downstream.invoke)
+		return nil, client(ctx, "/down.Service/Method", "request", nil, nil, downstream.invoke)
+	}
+	_, _ = server(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/up.Service/Method"}, handler)
+
+	events := transport.Errors()
+	if len(events) != 3 {
+		t.Fatalf("Expected 3 events, got %d", len(events))
+	}
+	expected := map[string]map[string]interface{}{
+		"/plain.Service/Method": {"server": 1, "client": nil},
+		"/down.Service/Method":  {"server": 1, "client": 1},
+		"/up.Service/Method":    {"server": 1, "client": nil},
+	}
+	for _, event := range events {
+		for name, runs := range expected[event.Request.URL] {
+			if event.Extra[name] != runs {
+				t.Errorf("Expected the %s processors to run %v times for %s, got %v", name, runs, event.Request.URL, event.Extra[name])
+			}
+		}
+	}
+}
@@ -3,9 +3,12 @@ package grpc_sentry
 
 import (
 	"context"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 
 	"google.golang.org/grpc"
 )
@@ -19,11 +22,13 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 		invoker grpc.UnaryInvoker,
 		callOpts ...grpc.CallOption) error {
 
-		hub := sentry.GetHubFromContext(ctx)
-		if hub == nil {
-			hub = sentry.CurrentHub().Clone()
-			ctx = sentry.SetHubOnContext(ctx, hub)
+		if !o.methods.instrumented(method) {
+			return invoker(ctx, method, req, reply, cc, callOpts...)
 		}
+		o := o.forMethod(method)
+		start := time.Now()
+
+		hub, events, ctx := hubForCall(ctx, o)
 
 		operationName := defaultClientOperationName
 		if o.OperationNameOverride != "" {
@@ -33,23 +38,44 @@ func UnaryClientInterceptor(opts ...Option) grpc.UnaryClientInterceptor {
 		span := sentry.StartSpan(ctx, operationName, sentry.WithDescription(method))
 		span.SetData("grpc.request.method", method)
 		ctx = span.Context()
-		md, ok := metadata.FromOutgoingContext(ctx)
-		if ok {
-			md.Append(sentry.SentryTraceHeader, span.ToSentryTrace())
-			md.Append(sentry.SentryBaggageHeader, span.ToBaggage())
-		} else {
-			md = metadata.Pairs(
-				sentry.SentryTraceHeader, span.ToSentryTrace(),
-				sentry.SentryBaggageHeader, span.ToBaggage(),
-			)
-		}
-		ctx = metadata.NewOutgoingContext(ctx, md)
+		// The metadata of the caller is captured without the trace headers sent along with it.
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ctx = metadata.NewOutgoingContext(ctx, metadata.Join(md, metadata.Pairs(
+			sentry.SentryTraceHeader, span.ToSentryTrace(),
+			sentry.SentryBaggageHeader, span.ToBaggage(),
+		)))
 		defer span.Finish()
 
+		// The peer and the trailer are always retrieved, the latter as it carries the full status of failed calls.
+		var p peer.Peer
+		var header, trailer metadata.MD
+		callOpts = append(callOpts[:len(callOpts):len(callOpts)], grpc.Peer(&p), grpc.Trailer(&trailer))
+
+		attachCallDetails(events, clientCallDetails(ctx, cc, md, method, callTypeUnary, callOpts, &p, o))
+		events.addEventProcessor(addStatusDetails)
+		recordMetadata(hub.Scope(), span, o, md)
+
+		if o.CaptureRequestBody {
+			attachRequestBody(events, method, req, o)
+		}
+
+		if capturesResponseMetadata(o) {
+			callOpts = append(callOpts, grpc.Header(&header))
+		}
+
 		err := invoker(ctx, method, req, reply, cc, callOpts...)
 
-		if err != nil && o.ReportOn(err) {
-			hub.CaptureException(err)
+		call := ReportInfo{FullMethod: method, Request: req, Duration: time.Since(start)}
+		if o.shouldReport(ctx, call, err) {
+			if o.CaptureResponseBody && hasResponse(reply) {
+				attachResponseBody(events, method, reply, o)
+			}
+			if capturesResponseMetadata(o) {
+				attachResponseMetadata(hub.Scope(), events, o, header, trailer)
+			}
+			attachDownstreamStatus(events, trailer)
+
+			captureError(ctx, hub, o, method, err)
 		}
 
 		return err
@@ -65,11 +91,13 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 		streamer grpc.Streamer,
 		callOpts ...grpc.CallOption) (grpc.ClientStream, error) {
 
-		hub := sentry.GetHubFromContext(ctx)
-		if hub == nil {
-			hub = sentry.CurrentHub().Clone()
-			ctx = sentry.SetHubOnContext(ctx, hub)
+		if !o.methods.instrumented(method) {
+			return streamer(ctx, desc, cc, method, callOpts...)
 		}
+		o := o.forMethod(method)
+		start := time.Now()
+
+		hub, events, ctx := hubForCall(ctx, o)
 
 		operationName := defaultClientOperationName
 		if o.OperationNameOverride != "" {
@@ -79,25 +107,34 @@ func StreamClientInterceptor(opts ...Option) grpc.StreamClientInterceptor {
 		span := sentry.StartSpan(ctx, operationName, sentry.WithDescription(method))
 		span.SetData("grpc.request.method", method)
 		ctx = span.Context()
-		md, ok := metadata.FromOutgoingContext(ctx)
-		if ok {
-			md.Append(sentry.SentryTraceHeader, span.ToSentryTrace())
-			md.Append(sentry.SentryBaggageHeader, span.ToBaggage())
-		} else {
-			md = metadata.Pairs(
-				sentry.SentryTraceHeader, span.ToSentryTrace(),
-				sentry.SentryBaggageHeader, span.ToBaggage(),
-			)
-		}
-		ctx = metadata.NewOutgoingContext(ctx, md)
-		defer span.Finish()
+		// The metadata of the caller is captured without the trace headers sent along with it.
+		md, _ := metadata.FromOutgoingContext(ctx)
+		ctx = metadata.NewOutgoingContext(ctx, metadata.Join(md, metadata.Pairs(
+			sentry.SentryTraceHeader, span.ToSentryTrace(),
+			sentry.SentryBaggageHeader, span.ToBaggage(),
+		)))
 
-		clientStream, err := streamer(ctx, desc, cc, method, callOpts...)
+		var p peer.Peer
+		callOpts = append(callOpts[:len(callOpts):len(callOpts)], grpc.Peer(&p))
+
+		callType := callTypeOf(desc.ClientStreams, desc.ServerStreams)
+		attachCallDetails(events, clientCallDetails(ctx, cc, md, method, callType, callOpts, &p, o))
+		events.addEventProcessor(addStatusDetails)
+		recordMetadata(hub.Scope(), span, o, md)
 
-		if err != nil && o.ReportOn(err) {
-			hub.CaptureException(err)
+		clientStream, err := streamer(ctx, desc, cc, method, callOpts...)
+		if err != nil {
+			call := ReportInfo{FullMethod: method, Duration: time.Since(start)}
+			if o.shouldReport(ctx, call, err) {
+				captureError(ctx, hub, o, method, err)
+			}
+
+			span.Status = toSpanStatus(status.Code(err))
+			span.Finish()
+			return clientStream, err
 		}
 
-		return clientStream, err
+		// The span is finished by the wrapped stream once the stream ends.
+		return newClientStream(ctx, clientStream, desc, hub, events, span, o, method, start), nil
 	}
 }
@@ -3,13 +3,18 @@ package grpc_sentry
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/getsentry/sentry-go"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/dynamicpb"
 )
 
 // mockUnaryInvoker is a mock invoker for testing unary client interceptors
@@ -210,3 +215,49 @@ func TestReportAlways_Client(t *testing.T) {
 		})
 	}
 }
+
+func TestUnaryClientInterceptor_ResponseBody(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryClientInterceptor(WithCaptureResponseBody(true), WithReportOn(ReportOnCodes(codes.Internal)))
+	reply := dynamicpb.NewMessage(testAccountDescriptor(t))
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		// Simulate a reply partially filled before the call failed
+		_ = protojson.Unmarshal([]byte(`{"userName": "jane"}`), reply.(proto.Message))
+		if method == "/test.Service/NotFound" {
+			return status.Error(codes.NotFound, "not found")
+		}
+		return status.Error(codes.Internal, "internal")
+	}
+
+	_ = interceptor(ctx, "/test.Service/NotFound", "request", reply, nil, invoker)
+	_ = interceptor(ctx, "/test.Service/Method", "request", reply, nil, invoker)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected only the reported error to be captured, got %d events", len(events))
+	}
+	data, _ := events[0].Contexts["response"]["data"].(string)
+	if !strings.Contains(data, "jane") {
+		t.Errorf("Expected the partial reply to be attached, got %q", data)
+	}
+}
+
+func TestUnaryClientInterceptor_RequestBody(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryClientInterceptor()
+	invoker := &mockUnaryInvoker{err: status.Error(codes.Unavailable, "unavailable")}
+	req := newTestAccount(t, `{"userName": "jane", "password": "hunter2"}`)
+	_ = interceptor(ctx, "/test.Service/Method", req, nil, nil, invoker.invoke)
+
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Request == nil {
+		t.Fatalf("Expected 1 event with request data, got %v", events)
+	}
+	if data := events[0].Request.Data; !strings.Contains(data, "jane") || strings.Contains(data, "hunter2") {
+		t.Errorf("Expected outgoing request to be rendered and redacted, got %s", data)
+	}
+}
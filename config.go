@@ -1,7 +1,15 @@
 // SPDX-License-Identifier: Apache-2.0
 package grpc_sentry
 
-import "time"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/codes"
+)
 
 type Option interface {
 	Apply(*options)
@@ -14,16 +22,90 @@ func newConfig(opts []Option) *options {
 	for _, o := range opts {
 		o.Apply(c)
 	}
+	c.validate()
+
+	c.methods = newMethodFilter(c)
+	c.resolved = &sync.Map{}
+	c.resolvedCount = &atomic.Int64{}
+
+	return c
+}
 
-	// Validate configuration to prevent runtime issues
+// validate fixes up the configuration to prevent runtime issues.
+func (c *options) validate() {
 	if c.Timeout <= 0 {
 		c.Timeout = 1 * time.Second // Ensure minimum timeout
 	}
 	if c.ReportOn == nil {
 		c.ReportOn = ReportAlways // Ensure ReportOn is never nil
 	}
+	if c.TransactionNamer == nil {
+		c.TransactionNamer = FullMethodName
+		c.TransactionSource = sentry.SourceRoute
+	}
+	if c.TransactionSource == "" {
+		c.TransactionSource = sentry.SourceCustom
+	}
+	if c.MaxStreamMessageSpans < 0 {
+		c.MaxStreamMessageSpans = 0
+	}
+	if c.StreamMessageSampleRate < 0 || c.StreamMessageSampleRate > 1 {
+		c.StreamMessageSampleRate = 1.0 // Ensure the sample rate is a probability
+	}
+	if c.RecoveryHandler == nil {
+		c.RecoveryHandler = RecoverWithCode(codes.Internal) // Ensure panics always map to an error
+	}
+}
 
-	return c
+// shouldReport reports whether err, returned by the call described by info, should be sent to Sentry.
+func (c *options) shouldReport(ctx context.Context, info ReportInfo, err error) bool {
+	if err == nil {
+		return false
+	}
+	if c.ReportPredicate != nil {
+		return c.ReportPredicate(ctx, info, err)
+	}
+	return c.ReportOn(err)
+}
+
+// forMethod returns the options that apply to calls of fullMethod, taking the overrides registered with
+// WithMethodOptions into account. The options of methods matching an override are resolved once and cached, up to
+// maxCachedMethods, so that the overrides are not applied again on every call.
+func (c *options) forMethod(fullMethod string) *options {
+	if len(c.methodOverrides) == 0 {
+		return c
+	}
+	if r, ok := c.resolved.Load(fullMethod); ok {
+		return r.(*options)
+	}
+
+	var matched []methodOverride
+	for _, override := range c.methodOverrides {
+		if override.pattern.match(fullMethod) {
+			matched = append(matched, override)
+		}
+	}
+	if len(matched) == 0 {
+		return c
+	}
+
+	m := *c
+	m.methodOverrides = nil
+	for _, override := range matched {
+		for _, o := range override.opts {
+			o.Apply(&m)
+		}
+	}
+	m.validate()
+
+	if c.resolvedCount.Load() >= maxCachedMethods {
+		return &m
+	}
+	r, loaded := c.resolved.LoadOrStore(fullMethod, &m)
+	if !loaded {
+		c.resolvedCount.Add(1)
+	}
+	return r.(*options)
 }
 
 type repanicOption struct {
@@ -70,12 +152,27 @@ type reportOnOption struct {
 
 func (r *reportOnOption) Apply(o *options) {
 	o.ReportOn = r.ReportOn
+	o.ReportPredicate = nil
 }
 
 func WithReportOn(r reporter) Option {
 	return &reportOnOption{ReportOn: r}
 }
 
+type reportPredicateOption struct {
+	ReportPredicate ReportPredicate
+}
+
+func (r *reportPredicateOption) Apply(o *options) {
+	o.ReportPredicate = r.ReportPredicate
+}
+
+// WithReportPredicate decides which errors are reported using a predicate that receives the context and details
+// of the call. It replaces any reporter function set with WithReportOn.
+func WithReportPredicate(p ReportPredicate) Option {
+	return &reportPredicateOption{ReportPredicate: p}
+}
+
 type operationNameOverride struct {
 	OperationNameOverride string
 }
@@ -99,3 +196,558 @@ func (c *captureRequestBodyOption) Apply(o *options) {
 func WithCaptureRequestBody(b bool) Option {
 	return &captureRequestBodyOption{CaptureRequestBody: b}
 }
+
+type recoveryHandlerOption struct {
+	RecoveryHandler RecoveryHandlerFunc
+}
+
+func (r *recoveryHandlerOption) Apply(o *options) {
+	o.RecoveryHandler = r.RecoveryHandler
+}
+
+func WithRecoveryHandler(f RecoveryHandlerFunc) Option {
+	return &recoveryHandlerOption{RecoveryHandler: f}
+}
+
+type streamErrorBreadcrumbsOption struct {
+	StreamErrorBreadcrumbs bool
+}
+
+func (s *streamErrorBreadcrumbsOption) Apply(o *options) {
+	o.StreamErrorBreadcrumbs = s.StreamErrorBreadcrumbs
+}
+
+func WithStreamErrorBreadcrumbs(b bool) Option {
+	return &streamErrorBreadcrumbsOption{StreamErrorBreadcrumbs: b}
+}
+
+type captureStreamErrorsOption struct {
+	CaptureStreamErrors bool
+}
+
+func (c *captureStreamErrorsOption) Apply(o *options) {
+	o.CaptureStreamErrors = c.CaptureStreamErrors
+}
+
+func WithCaptureStreamErrors(b bool) Option {
+	return &captureStreamErrorsOption{CaptureStreamErrors: b}
+}
+
+type streamMessageSpansOption struct {
+	StreamMessageSpans bool
+}
+
+func (s *streamMessageSpansOption) Apply(o *options) {
+	o.StreamMessageSpans = s.StreamMessageSpans
+}
+
+// WithStreamMessageSpans traces every message sent or received on a stream as a child span of the stream, and
+// records the number of bytes sent and received on the stream.
+func WithStreamMessageSpans(b bool) Option {
+	return &streamMessageSpansOption{StreamMessageSpans: b}
+}
+
+type maxStreamMessageSpansOption struct {
+	MaxStreamMessageSpans int
+}
+
+func (m *maxStreamMessageSpansOption) Apply(o *options) {
+	o.MaxStreamMessageSpans = m.MaxStreamMessageSpans
+}
+
+func WithMaxStreamMessageSpans(n int) Option {
+	return &maxStreamMessageSpansOption{MaxStreamMessageSpans: n}
+}
+
+type streamMessageSampleRateOption struct {
+	StreamMessageSampleRate float64
+}
+
+func (s *streamMessageSampleRateOption) Apply(o *options) {
+	o.StreamMessageSampleRate = s.StreamMessageSampleRate
+}
+
+func WithStreamMessageSampleRate(r float64) Option {
+	return &streamMessageSampleRateOption{StreamMessageSampleRate: r}
+}
+
+type ignoreMethodsOption struct {
+	IgnoreMethods []string
+}
+
+func (i *ignoreMethodsOption) Apply(o *options) {
+	o.IgnoreMethods = append(o.IgnoreMethods[:len(o.IgnoreMethods):len(o.IgnoreMethods)], i.IgnoreMethods...)
+}
+
+// WithIgnoreMethods excludes the methods matching any of the patterns from instrumentation. Patterns may be full
+// method names ("/grpc.health.v1.Health/Check"), service names ("grpc.health.v1.Health") or globs ("/billing.*").
+func WithIgnoreMethods(patterns ...string) Option {
+	return &ignoreMethodsOption{IgnoreMethods: patterns}
+}
+
+type onlyMethodsOption struct {
+	OnlyMethods []string
+}
+
+func (m *onlyMethodsOption) Apply(o *options) {
+	o.OnlyMethods = append(o.OnlyMethods[:len(o.OnlyMethods):len(o.OnlyMethods)], m.OnlyMethods...)
+}
+
+// WithOnlyMethods restricts instrumentation to the methods matching any of the patterns, using the same patterns as
+// WithIgnoreMethods.
+func WithOnlyMethods(patterns ...string) Option {
+	return &onlyMethodsOption{OnlyMethods: patterns}
+}
+
+type ignoreDefaultMethodsOption struct {
+	IgnoreDefaultMethods bool
+}
+
+func (i *ignoreDefaultMethodsOption) Apply(o *options) {
+	o.IgnoreDefaultMethods = i.IgnoreDefaultMethods
+}
+
+// WithDefaultIgnoredMethods configures whether the gRPC health checking and reflection services are ignored, which
+// they are by default.
+func WithDefaultIgnoredMethods(b bool) Option {
+	return &ignoreDefaultMethodsOption{IgnoreDefaultMethods: b}
+}
+
+type methodOptionsOption struct {
+	Pattern string
+	Options []Option
+}
+
+func (m *methodOptionsOption) Apply(o *options) {
+	o.methodOverrides = append(o.methodOverrides[:len(o.methodOverrides):len(o.methodOverrides)], methodOverride{
+		pattern: compileMethodPattern(m.Pattern),
+		opts:    m.Options,
+	})
+}
+
+// WithMethodOptions applies opts on top of the other options for the methods matching pattern, which accepts the
+// same patterns as WithIgnoreMethods. Overrides are applied in the order they are given. Options deciding which
+// methods are instrumented have no effect within an override.
+func WithMethodOptions(pattern string, opts ...Option) Option {
+	return &methodOptionsOption{Pattern: pattern, Options: opts}
+}
+
+type maxCapturedStreamMessagesOption struct {
+	MaxCapturedStreamMessages int
+}
+
+func (m *maxCapturedStreamMessagesOption) Apply(o *options) {
+	o.MaxCapturedStreamMessages = m.MaxCapturedStreamMessages
+}
+
+// WithMaxCapturedStreamMessages sets how many of the first messages sent on a client stream are attached to reported
+// errors when the request body is captured.
+func WithMaxCapturedStreamMessages(n int) Option {
+	return &maxCapturedStreamMessagesOption{MaxCapturedStreamMessages: n}
+}
+
+type captureResponseBodyOption struct {
+	CaptureResponseBody bool
+}
+
+func (c *captureResponseBodyOption) Apply(o *options) {
+	o.CaptureResponseBody = c.CaptureResponseBody
+}
+
+// WithCaptureResponseBody configures whether the response of unary calls, which may be partially filled when the
+// call fails, is attached to reported errors. It is rendered and redacted like the request body.
+func WithCaptureResponseBody(b bool) Option {
+	return &captureResponseBodyOption{CaptureResponseBody: b}
+}
+
+type bodyProtoNamesOption struct {
+	BodyProtoNames bool
+}
+
+func (b *bodyProtoNamesOption) Apply(o *options) {
+	o.BodyProtoNames = b.BodyProtoNames
+}
+
+// WithBodyProtoNames configures whether captured protobuf messages are rendered with the field names from the
+// .proto file rather than their JSON names.
+func WithBodyProtoNames(b bool) Option {
+	return &bodyProtoNamesOption{BodyProtoNames: b}
+}
+
+type bodyEmitDefaultsOption struct {
+	BodyEmitDefaults bool
+}
+
+func (b *bodyEmitDefaultsOption) Apply(o *options) {
+	o.BodyEmitDefaults = b.BodyEmitDefaults
+}
+
+// WithBodyEmitDefaults configures whether captured protobuf messages include fields set to their default value.
+func WithBodyEmitDefaults(b bool) Option {
+	return &bodyEmitDefaultsOption{BodyEmitDefaults: b}
+}
+
+type bodyMaxDepthOption struct {
+	BodyMaxDepth int
+}
+
+func (b *bodyMaxDepthOption) Apply(o *options) {
+	o.BodyMaxDepth = b.BodyMaxDepth
+}
+
+// WithBodyMaxDepth limits how deep objects and arrays of captured messages are rendered. Use zero for no limit.
+func WithBodyMaxDepth(n int) Option {
+	return &bodyMaxDepthOption{BodyMaxDepth: n}
+}
+
+type redactFieldPathsOption struct {
+	RedactFieldPaths []string
+}
+
+func (r *redactFieldPathsOption) Apply(o *options) {
+	o.RedactFieldPaths = append(o.RedactFieldPaths[:len(o.RedactFieldPaths):len(o.RedactFieldPaths)], r.RedactFieldPaths...)
+}
+
+// WithRedactFieldPaths redacts the fields of captured messages found at the given paths. A path lists the .proto
+// field names leading to the field from the root message, separated by dots, e.g. "credentials.token"; map keys and
+// list indexes are not part of the path. Paths may contain glob patterns such as "*.secret".
+func WithRedactFieldPaths(paths ...string) Option {
+	return &redactFieldPathsOption{RedactFieldPaths: paths}
+}
+
+type redactFieldNamesOption struct {
+	RedactFieldNames []string
+}
+
+func (r *redactFieldNamesOption) Apply(o *options) {
+	o.RedactFieldNames = append(o.RedactFieldNames[:len(o.RedactFieldNames):len(o.RedactFieldNames)], r.RedactFieldNames...)
+}
+
+// WithRedactFieldNames redacts the fields of captured messages whose .proto name matches any of the case-insensitive
+// glob patterns, e.g. "password" or "*token*", at any depth.
+func WithRedactFieldNames(patterns ...string) Option {
+	return &redactFieldNamesOption{RedactFieldNames: patterns}
+}
+
+type payloadScrubberOption struct {
+	PayloadScrubber PayloadScrubber
+}
+
+func (p *payloadScrubberOption) Apply(o *options) {
+	o.PayloadScrubber = p.PayloadScrubber
+}
+
+// WithPayloadScrubber sets a function called on every captured payload, after fields have been redacted, to remove
+// or rewrite anything else before it is sent to Sentry.
+func WithPayloadScrubber(s PayloadScrubber) Option {
+	return &payloadScrubberOption{PayloadScrubber: s}
+}
+
+type maxPayloadSizeOption struct {
+	MaxPayloadSize int
+}
+
+func (m *maxPayloadSizeOption) Apply(o *options) {
+	o.MaxPayloadSize = m.MaxPayloadSize
+}
+
+// WithMaxPayloadSize sets the maximum size in bytes of a captured payload once rendered. A message over the limit is
+// replaced by its type and size, and so is a message whose wire format alone is 16 times over the limit, without
+// being rendered at all. Use zero for no limit.
+func WithMaxPayloadSize(n int) Option {
+	return &maxPayloadSizeOption{MaxPayloadSize: n}
+}
+
+type maxPayloadFieldSizeOption struct {
+	MaxPayloadFieldSize int
+}
+
+func (m *maxPayloadFieldSizeOption) Apply(o *options) {
+	o.MaxPayloadFieldSize = m.MaxPayloadFieldSize
+}
+
+// WithMaxPayloadFieldSize truncates string and bytes fields of captured messages to n bytes. Use zero for no limit.
+func WithMaxPayloadFieldSize(n int) Option {
+	return &maxPayloadFieldSizeOption{MaxPayloadFieldSize: n}
+}
+
+type maxRepeatedFieldItemsOption struct {
+	MaxRepeatedFieldItems int
+}
+
+func (m *maxRepeatedFieldItemsOption) Apply(o *options) {
+	o.MaxRepeatedFieldItems = m.MaxRepeatedFieldItems
+}
+
+// WithMaxRepeatedFieldItems truncates repeated fields of captured messages to n items. Use zero for no limit.
+func WithMaxRepeatedFieldItems(n int) Option {
+	return &maxRepeatedFieldItemsOption{MaxRepeatedFieldItems: n}
+}
+
+type captureMetadataOption struct {
+	CaptureMetadata bool
+}
+
+func (c *captureMetadataOption) Apply(o *options) {
+	o.CaptureMetadata = c.CaptureMetadata
+}
+
+// WithCaptureMetadata configures whether gRPC metadata is captured: the incoming metadata in the server interceptors,
+// and the outgoing metadata along with the response header and trailer in the client interceptors. Only the keys
+// allowed by WithAllowedMetadata and not denied by WithDeniedMetadata are captured.
+func WithCaptureMetadata(b bool) Option {
+	return &captureMetadataOption{CaptureMetadata: b}
+}
+
+type allowedMetadataOption struct {
+	AllowedMetadata []string
+}
+
+func (a *allowedMetadataOption) Apply(o *options) {
+	o.AllowedMetadata = append(o.AllowedMetadata[:len(o.AllowedMetadata):len(o.AllowedMetadata)], a.AllowedMetadata...)
+}
+
+// WithAllowedMetadata restricts captured metadata to the keys matching any of the case-insensitive glob patterns,
+// e.g. "x-request-id" or "x-tenant-*".
+func WithAllowedMetadata(keys ...string) Option {
+	return &allowedMetadataOption{AllowedMetadata: keys}
+}
+
+type deniedMetadataOption struct {
+	DeniedMetadata []string
+}
+
+func (d *deniedMetadataOption) Apply(o *options) {
+	o.DeniedMetadata = append(o.DeniedMetadata[:len(o.DeniedMetadata):len(o.DeniedMetadata)], d.DeniedMetadata...)
+}
+
+// WithDeniedMetadata prevents the metadata keys matching any of the case-insensitive glob patterns from being
+// captured, even when they are allowed or promoted to tags.
+func WithDeniedMetadata(keys ...string) Option {
+	return &deniedMetadataOption{DeniedMetadata: keys}
+}
+
+type denyDefaultMetadataOption struct {
+	DenyDefaultMetadata bool
+}
+
+func (d *denyDefaultMetadataOption) Apply(o *options) {
+	o.DenyDefaultMetadata = d.DenyDefaultMetadata
+}
+
+// WithDefaultDeniedMetadata configures whether the metadata keys usually carrying credentials, such as
+// "authorization" and "cookie", are denied, which they are by default.
+func WithDefaultDeniedMetadata(b bool) Option {
+	return &denyDefaultMetadataOption{DenyDefaultMetadata: b}
+}
+
+type metadataTagsOption struct {
+	MetadataTags []string
+}
+
+func (m *metadataTagsOption) Apply(o *options) {
+	o.MetadataTags = append(o.MetadataTags[:len(o.MetadataTags):len(o.MetadataTags)], m.MetadataTags...)
+}
+
+// WithMetadataTags promotes the values of the given metadata keys to tags of events and spans, whether or not
+// metadata is otherwise captured.
+func WithMetadataTags(keys ...string) Option {
+	return &metadataTagsOption{MetadataTags: keys}
+}
+
+type userExtractorOption struct {
+	UserExtractor UserExtractor
+}
+
+func (u *userExtractorOption) Apply(o *options) {
+	o.UserExtractor = u.UserExtractor
+}
+
+// WithUserExtractor sets the user of the events captured by the server interceptors to the one identified by the
+// extractor, e.g. UserFromTLSClientCert, UserFromALTS or UserFromJWTClaims, or a combination built with FirstUser.
+func WithUserExtractor(extractor UserExtractor) Option {
+	return &userExtractorOption{UserExtractor: extractor}
+}
+
+type tagPrefixOption struct {
+	TagPrefix string
+}
+
+func (t *tagPrefixOption) Apply(o *options) {
+	o.TagPrefix = t.TagPrefix
+}
+
+// WithTagPrefix prepends the prefix, e.g. "grpc.", to the keys of the grpc_tags values set as tags of reported
+// events.
+func WithTagPrefix(prefix string) Option {
+	return &tagPrefixOption{TagPrefix: prefix}
+}
+
+type maxTagValueLengthOption struct {
+	MaxTagValueLength int
+}
+
+func (m *maxTagValueLengthOption) Apply(o *options) {
+	o.MaxTagValueLength = m.MaxTagValueLength
+}
+
+// WithMaxTagValueLength sets the maximum number of characters of tag values set from grpc_tags, which defaults to
+// the 200 characters accepted by Sentry. Longer values are truncated.
+func WithMaxTagValueLength(n int) Option {
+	return &maxTagValueLengthOption{MaxTagValueLength: n}
+}
+
+type levelsOption struct {
+	Levels map[codes.Code]sentry.Level
+}
+
+func (l *levelsOption) Apply(o *options) {
+	levels := make(map[codes.Code]sentry.Level, len(o.Levels)+len(l.Levels))
+	for code, level := range o.Levels {
+		levels[code] = level
+	}
+	for code, level := range l.Levels {
+		levels[code] = level
+	}
+	o.Levels = levels
+}
+
+// WithLevels overrides the level of the events reporting errors with the given status codes. Codes that are not
+// given keep their default level, e.g. NotFound is reported as info and InvalidArgument as a warning. Recovered
+// panics have no status code and are always reported as fatal, unless a BeforeCapture hook changes their level.
+func WithLevels(levels map[codes.Code]sentry.Level) Option {
+	return &levelsOption{Levels: levels}
+}
+
+type levelFuncOption struct {
+	LevelFunc LevelFunc
+}
+
+func (l *levelFuncOption) Apply(o *options) {
+	o.LevelFunc = l.LevelFunc
+}
+
+// WithLevelFunc decides the level of reported events with the function, falling back to the levels table when it
+// returns an empty level.
+func WithLevelFunc(f LevelFunc) Option {
+	return &levelFuncOption{LevelFunc: f}
+}
+
+type fingerprinterOption struct {
+	Fingerprinter Fingerprinter
+}
+
+func (f *fingerprinterOption) Apply(o *options) {
+	o.Fingerprinter = f.Fingerprinter
+}
+
+// WithFingerprinter replaces DefaultFingerprint to group reported errors into issues. A nil Fingerprinter leaves the
+// grouping to Sentry.
+func WithFingerprinter(f Fingerprinter) Option {
+	return &fingerprinterOption{Fingerprinter: f}
+}
+
+type hubOption struct {
+	Hub *sentry.Hub
+}
+
+func (h *hubOption) Apply(o *options) {
+	o.Hub = h.Hub
+}
+
+// WithHub reports RPCs through a clone of the hub instead of the hub found on the context or sentry.CurrentHub().
+func WithHub(hub *sentry.Hub) Option {
+	return &hubOption{Hub: hub}
+}
+
+type clientOption struct {
+	Client *sentry.Client
+}
+
+func (c *clientOption) Apply(o *options) {
+	o.Client = c.Client
+}
+
+// WithClient sends the events and transactions of RPCs with the client, keeping the scope of the hub found on the
+// context. This lets several servers in one process report to different Sentry projects.
+func WithClient(client *sentry.Client) Option {
+	return &clientOption{Client: client}
+}
+
+type hubFactoryOption struct {
+	HubFactory HubFactory
+}
+
+func (h *hubFactoryOption) Apply(o *options) {
+	o.HubFactory = h.HubFactory
+}
+
+// WithHubFactory picks the hub of every RPC with the factory. The returned hub is cloned, so it may be shared
+// between calls.
+func WithHubFactory(factory HubFactory) Option {
+	return &hubFactoryOption{HubFactory: factory}
+}
+
+type clientRouterOption struct {
+	ClientRouter ClientRouter
+}
+
+func (c *clientRouterOption) Apply(o *options) {
+	o.ClientRouter = c.ClientRouter
+}
+
+// WithClientRouter reports the RPCs handled by the server interceptors through the client picked by the router,
+// e.g. one built with RouteByService or RouteByMetadata. The router takes precedence over WithClient.
+func WithClientRouter(router ClientRouter) Option {
+	return &clientRouterOption{ClientRouter: router}
+}
+
+type beforeCaptureOption struct {
+	BeforeCapture BeforeCapture
+}
+
+func (b *beforeCaptureOption) Apply(o *options) {
+	o.BeforeCapture = b.BeforeCapture
+}
+
+// WithBeforeCapture calls the hook right before the interceptors capture an error or a recovered panic, with the
+// scope of the event so that it can be enriched with request-specific knowledge. The event is dropped when the hook
+// returns false. Panics that are not errors are handed to the hook as an error formatting the recovered value.
+func WithBeforeCapture(hook BeforeCapture) Option {
+	return &beforeCaptureOption{BeforeCapture: hook}
+}
+
+type eventProcessorsOption struct {
+	EventProcessors []sentry.EventProcessor
+}
+
+func (e *eventProcessorsOption) Apply(o *options) {
+	o.EventProcessors = append(o.EventProcessors[:len(o.EventProcessors):len(o.EventProcessors)], e.EventProcessors...)
+}
+
+// WithEventProcessors adds the processors to every RPC, so that they run for the events and transactions captured
+// while the RPC is in progress, including those captured by handlers through the hub of their context, and for no
+// other event. They run once the interceptors have shaped the event, which happens after the processors of the scope
+// and the client and before BeforeSend. The events of a call nested in another one, e.g. a client call made by a
+// handler, run the processors of the nested call followed by those of each enclosing call, each once.
+func WithEventProcessors(processors ...sentry.EventProcessor) Option {
+	return &eventProcessorsOption{EventProcessors: processors}
+}
+
+type transactionNamerOption struct {
+	TransactionNamer  TransactionNamer
+	TransactionSource sentry.TransactionSource
+}
+
+func (t *transactionNamerOption) Apply(o *options) {
+	o.TransactionNamer = t.TransactionNamer
+	o.TransactionSource = t.TransactionSource
+}
+
+// WithTransactionNamer names the transactions of the server interceptors with the namer, whose names come from the
+// source. Names identifying the method being called, like those of the built-in FullMethodName, ServiceMethodName
+// and MethodName, are from sentry.SourceRoute; other names are usually from sentry.SourceCustom, which is also used
+// when the source is empty.
+func WithTransactionNamer(namer TransactionNamer, source sentry.TransactionSource) Option {
+	return &transactionNamerOption{TransactionNamer: namer, TransactionSource: source}
+}
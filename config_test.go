@@ -2,8 +2,13 @@
 package grpc_sentry
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewConfig_DefaultOptions(t *testing.T) {
@@ -178,3 +183,160 @@ func TestOption_Apply(t *testing.T) {
 		t.Errorf("Expected CaptureRequestBody to be false after applying option, got %v", config.CaptureRequestBody)
 	}
 }
+
+func TestNewConfig_WithRecoveryHandler(t *testing.T) {
+	config := newConfig([]Option{WithRecoveryHandler(RecoverWithCode(codes.Unavailable))})
+
+	err := config.RecoveryHandler(context.Background(), "panic", nil)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected RecoveryHandler to return %s, got %v", codes.Unavailable, err)
+	}
+
+	// Test with nil RecoveryHandler - should be set to the default
+	config = newConfig([]Option{WithRecoveryHandler(nil)})
+	if config.RecoveryHandler == nil {
+		t.Error("Expected RecoveryHandler to be set to the default, got nil")
+	}
+}
+
+func TestNewConfig_WithStreamErrorOptions(t *testing.T) {
+	config := newConfig([]Option{WithStreamErrorBreadcrumbs(true), WithCaptureStreamErrors(true)})
+
+	if config.StreamErrorBreadcrumbs != true {
+		t.Errorf("Expected StreamErrorBreadcrumbs to be true, got %v", config.StreamErrorBreadcrumbs)
+	}
+	if config.CaptureStreamErrors != true {
+		t.Errorf("Expected CaptureStreamErrors to be true, got %v", config.CaptureStreamErrors)
+	}
+}
+
+func TestNewConfig_WithStreamMessageSpans(t *testing.T) {
+	config := newConfig([]Option{
+		WithStreamMessageSpans(true),
+		WithMaxStreamMessageSpans(10),
+		WithStreamMessageSampleRate(0.5),
+	})
+
+	if config.StreamMessageSpans != true {
+		t.Errorf("Expected StreamMessageSpans to be true, got %v", config.StreamMessageSpans)
+	}
+	if config.MaxStreamMessageSpans != 10 {
+		t.Errorf("Expected MaxStreamMessageSpans to be 10, got %v", config.MaxStreamMessageSpans)
+	}
+	if config.StreamMessageSampleRate != 0.5 {
+		t.Errorf("Expected StreamMessageSampleRate to be 0.5, got %v", config.StreamMessageSampleRate)
+	}
+
+	// Test with out of range values - should be clamped
+	config = newConfig([]Option{WithMaxStreamMessageSpans(-1), WithStreamMessageSampleRate(2)})
+	if config.MaxStreamMessageSpans != 0 {
+		t.Errorf("Expected MaxStreamMessageSpans to be 0, got %v", config.MaxStreamMessageSpans)
+	}
+	if config.StreamMessageSampleRate != 1.0 {
+		t.Errorf("Expected StreamMessageSampleRate to be 1.0, got %v", config.StreamMessageSampleRate)
+	}
+}
+
+func TestNewConfig_WithMethodOptions(t *testing.T) {
+	config := newConfig([]Option{
+		WithTimeout(2 * time.Second),
+		WithMethodOptions("/billing.*", WithWaitForDelivery(true)),
+		WithMethodOptions("/search.Search/Query", WithCaptureRequestBody(false)),
+		WithMethodOptions("billing.Invoices", WithTimeout(5*time.Second)),
+	})
+
+	billing := config.forMethod("/billing.Invoices/Create")
+	if billing.WaitForDelivery != true {
+		t.Errorf("Expected WaitForDelivery to be true for billing, got %v", billing.WaitForDelivery)
+	}
+	if billing.Timeout != 5*time.Second {
+		t.Errorf("Expected Timeout to be 5s for billing invoices, got %v", billing.Timeout)
+	}
+	if billing.CaptureRequestBody != true {
+		t.Errorf("Expected CaptureRequestBody to be true for billing, got %v", billing.CaptureRequestBody)
+	}
+	if cached := config.forMethod("/billing.Invoices/Create"); cached != billing {
+		t.Error("Expected resolved options to be cached")
+	}
+
+	search := config.forMethod("/search.Search/Query")
+	if search.CaptureRequestBody != false {
+		t.Errorf("Expected CaptureRequestBody to be false for search, got %v", search.CaptureRequestBody)
+	}
+	if search.WaitForDelivery != false {
+		t.Errorf("Expected WaitForDelivery to be false for search, got %v", search.WaitForDelivery)
+	}
+	if search.Timeout != 2*time.Second {
+		t.Errorf("Expected Timeout to be inherited for search, got %v", search.Timeout)
+	}
+
+	// The base configuration is left untouched
+	if config.WaitForDelivery != false || config.CaptureRequestBody != true {
+		t.Error("Expected base configuration not to be modified by method options")
+	}
+
+	// Without overrides the base configuration is returned as is
+	plain := newConfig([]Option{})
+	if plain.forMethod("/test.Service/Method") != plain {
+		t.Error("Expected base configuration to be returned without method options")
+	}
+}
+
+func TestNewConfig_WithMethodOptionsCache(t *testing.T) {
+	config := newConfig([]Option{WithMethodOptions("/billing.*", WithWaitForDelivery(true))})
+
+	for i := 0; i < 2*maxCachedMethods; i++ {
+		if config.forMethod(fmt.Sprintf("/unknown.Service/Method%d", i)) != config {
+			t.Fatal("Expected base configuration to be returned for methods without overrides")
+		}
+	}
+	if n := config.resolvedCount.Load(); n != 0 {
+		t.Errorf("Expected methods without overrides not to be cached, got %d", n)
+	}
+
+	for i := 0; i < 2*maxCachedMethods; i++ {
+		if !config.forMethod(fmt.Sprintf("/billing.Invoices/Method%d", i)).WaitForDelivery {
+			t.Fatal("Expected overrides to apply once the cache is full")
+		}
+	}
+	if n := config.resolvedCount.Load(); n != maxCachedMethods {
+		t.Errorf("Expected %d cached methods, got %d", maxCachedMethods, n)
+	}
+}
+
+func TestNewConfig_WithMethodOptionsValidation(t *testing.T) {
+	config := newConfig([]Option{WithMethodOptions("/test.*", WithTimeout(0), WithReportOn(nil))})
+
+	resolved := config.forMethod("/test.Service/Method")
+	if resolved.Timeout <= 0 {
+		t.Errorf("Expected Timeout to be positive, got %v", resolved.Timeout)
+	}
+	if resolved.ReportOn == nil {
+		t.Error("Expected ReportOn to be set to ReportAlways, got nil")
+	}
+}
+
+func TestNewConfig_WithReportPredicate(t *testing.T) {
+	ctx := context.Background()
+	info := ReportInfo{FullMethod: "/test.Service/Method"}
+	err := status.Error(codes.Internal, "internal")
+
+	config := newConfig([]Option{WithReportPredicate(func(context.Context, ReportInfo, error) bool { return false })})
+	if config.shouldReport(ctx, info, err) {
+		t.Error("Expected ReportPredicate to decide whether errors are reported")
+	}
+
+	// The option applied last wins
+	config = newConfig([]Option{
+		WithReportPredicate(func(context.Context, ReportInfo, error) bool { return false }),
+		WithReportOn(ReportAlways),
+	})
+	if !config.shouldReport(ctx, info, err) {
+		t.Error("Expected WithReportOn to replace an earlier ReportPredicate")
+	}
+
+	// Nil errors are never reported
+	if newConfig([]Option{}).shouldReport(ctx, info, nil) {
+		t.Error("Expected nil errors not to be reported")
+	}
+}
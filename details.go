@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// detailContexts names the contexts holding the well-known google.rpc error details.
+var detailContexts = map[protoreflect.FullName]string{
+	proto.MessageName(&errdetails.ErrorInfo{}):           "error_info",
+	proto.MessageName(&errdetails.BadRequest{}):          "bad_request",
+	proto.MessageName(&errdetails.DebugInfo{}):           "debug_info",
+	proto.MessageName(&errdetails.RetryInfo{}):           "retry_info",
+	proto.MessageName(&errdetails.QuotaFailure{}):        "quota_failure",
+	proto.MessageName(&errdetails.PreconditionFailure{}): "precondition_failure",
+}
+
+// unknownDetailsContext is the context listing the details that are not well-known, or could not be decoded.
+const unknownDetailsContext = "status_details"
+
+// addStatusDetails is an event processor decoding the details of the gRPC status of the captured error. Well-known
+// details become contexts, ErrorInfo's reason and domain become tags, and the stack entries of DebugInfo become an
+// extra exception so that the stack of the failing server shows up next to the one of the caller.
+func addStatusDetails(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	if event.Type == "transaction" || hint == nil || hint.OriginalException == nil {
+		return event
+	}
+	s, ok := status.FromError(hint.OriginalException)
+	if !ok {
+		return event
+	}
+	return addDetails(event, s.Proto())
+}
+
+// addDetails adds the details of the status to the event as described by addStatusDetails.
+func addDetails(event *sentry.Event, s *spb.Status) *sentry.Event {
+	if len(s.GetDetails()) == 0 {
+		return event
+	}
+
+	if event.Contexts == nil {
+		event.Contexts = make(map[string]sentry.Context)
+	}
+	var unknown []interface{}
+	for _, detailAny := range s.GetDetails() {
+		detail, rendered := decodeDetail(detailAny)
+		if detail == nil {
+			unknown = append(unknown, rendered)
+			continue
+		}
+
+		name, ok := detailContexts[proto.MessageName(detail)]
+		if !ok {
+			unknown = append(unknown, rendered)
+			continue
+		}
+		delete(rendered, "@type")
+		event.Contexts[uniqueContextName(event.Contexts, name)] = rendered
+
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			if event.Tags == nil {
+				event.Tags = make(map[string]string)
+			}
+			if detail.GetReason() != "" {
+				event.Tags["error_info.reason"] = detail.GetReason()
+			}
+			if detail.GetDomain() != "" {
+				event.Tags["error_info.domain"] = detail.GetDomain()
+			}
+		case *errdetails.DebugInfo:
+			if len(detail.GetStackEntries()) > 0 {
+				event.Exception = append([]sentry.Exception{debugInfoException(detail)}, event.Exception...)
+			}
+		}
+	}
+	if len(unknown) > 0 {
+		event.Contexts[unknownDetailsContext] = sentry.Context{"details": unknown}
+	}
+	return event
+}
+
+// decodeDetail unpacks a status detail and renders it with its type. The message is nil when the type of the
+// detail is not linked into the binary.
+func decodeDetail(detailAny *anypb.Any) (proto.Message, sentry.Context) {
+	detail, err := detailAny.UnmarshalNew()
+	if err != nil {
+		return nil, sentry.Context{"@type": detailAny.GetTypeUrl(), "error": err.Error()}
+	}
+	rendered := renderDetail(detail)
+	rendered["@type"] = detailAny.GetTypeUrl()
+	return detail, rendered
+}
+
+// renderDetail renders a detail message as a context, using the protojson representation of its fields.
+func renderDetail(detail proto.Message) sentry.Context {
+	c := sentry.Context{}
+	data, err := protojson.MarshalOptions{UseProtoNames: true}.Marshal(detail)
+	if err == nil {
+		err = json.Unmarshal(data, &c)
+	}
+	if err != nil {
+		c["error"] = err.Error()
+	}
+	return c
+}
+
+// uniqueContextName returns name, suffixed with a number when the event already has a context of that name.
+func uniqueContextName(contexts map[string]sentry.Context, name string) string {
+	unique := name
+	for i := 2; ; i++ {
+		if _, exists := contexts[unique]; !exists {
+			return unique
+		}
+		unique = name + "_" + strconv.Itoa(i)
+	}
+}
+
+// debugInfoException turns the stack entries of a DebugInfo into an exception. Stack entries are listed from the
+// innermost call outwards while Sentry expects frames from the outermost call inwards.
+func debugInfoException(detail *errdetails.DebugInfo) sentry.Exception {
+	entries := detail.GetStackEntries()
+	frames := make([]sentry.Frame, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		frames = append(frames, sentry.Frame{Function: entries[i], InApp: true})
+	}
+	return sentry.Exception{
+		Type:       "DebugInfo",
+		Value:      detail.GetDetail(),
+		Stacktrace: &sentry.Stacktrace{Frames: frames},
+	}
+}
+
+// statusDetailsKey is the trailer carrying the full google.rpc.Status of a failed call.
+const statusDetailsKey = "grpc-status-details-bin"
+
+// downstreamStatusContext is the context holding the status returned by the server to a client call.
+const downstreamStatusContext = "downstream_status"
+
+// statusFromTrailer decodes the full status sent back by the server in the trailer of a call, if any.
+func statusFromTrailer(trailer metadata.MD) *spb.Status {
+	values := trailer.Get(statusDetailsKey)
+	if len(values) == 0 {
+		return nil
+	}
+	s := &spb.Status{}
+	if err := proto.Unmarshal([]byte(values[len(values)-1]), s); err != nil {
+		return nil
+	}
+	return s
+}
+
+// attachDownstreamStatus attaches the full status found in the trailer of a failed client call to the events
+// captured during the call. Its details are also decoded like those of the captured error when the error lost them
+// on its way back, e.g. because another interceptor replaced it.
+func attachDownstreamStatus(events *callEvents, trailer metadata.MD) {
+	s := statusFromTrailer(trailer)
+	if s == nil {
+		return
+	}
+
+	details := make([]interface{}, 0, len(s.GetDetails()))
+	for _, detailAny := range s.GetDetails() {
+		_, rendered := decodeDetail(detailAny)
+		details = append(details, rendered)
+	}
+	downstream := sentry.Context{
+		"code":    codes.Code(s.GetCode()).String(),
+		"message": s.GetMessage(),
+		"details": details,
+	}
+
+	events.addEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+		if event.Type == "transaction" {
+			return event
+		}
+		if event.Contexts == nil {
+			event.Contexts = make(map[string]sentry.Context)
+		}
+		event.Contexts[downstreamStatusContext] = downstream
+
+		var captured *status.Status
+		if hint != nil && hint.OriginalException != nil {
+			captured, _ = status.FromError(hint.OriginalException)
+		}
+		if len(captured.Proto().GetDetails()) == 0 {
+			event = addDetails(event, s)
+		}
+		return event
+	})
+}
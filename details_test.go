@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func testStatusWithDetails(t *testing.T, details ...protoadapt.MessageV1) error {
+	t.Helper()
+	s, err := status.New(codes.FailedPrecondition, "account is locked").WithDetails(details...)
+	if err != nil {
+		t.Fatalf("Failed to add status details: %v", err)
+	}
+	return s.Err()
+}
+
+func TestAddStatusDetails(t *testing.T) {
+	err := testStatusWithDetails(t,
+		&errdetails.ErrorInfo{Reason: "ACCOUNT_LOCKED", Domain: "accounts.example.com", Metadata: map[string]string{"account": "42"}},
+		&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{{Field: "user_name", Description: "too short"}}},
+		&errdetails.DebugInfo{Detail: "lock held", StackEntries: []string{"accounts.lock", "accounts.Update", "main.main"}},
+		&errdetails.RetryInfo{RetryDelay: durationpb.New(5 * time.Second)},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: "project:42"}}},
+		&errdetails.PreconditionFailure{Violations: []*errdetails.PreconditionFailure_Violation{{Type: "TOS", Subject: "user:42"}}},
+		wrapperspb.String("custom detail"),
+	)
+	event := &sentry.Event{Exception: []sentry.Exception{{Type: "*status.Error", Value: "account is locked"}}}
+	event = addStatusDetails(event, &sentry.EventHint{OriginalException: err})
+
+	for _, name := range []string{"error_info", "bad_request", "debug_info", "retry_info", "quota_failure", "precondition_failure"} {
+		if _, ok := event.Contexts[name]; !ok {
+			t.Errorf("Expected %s context, got %v", name, event.Contexts)
+		}
+	}
+	if got := event.Contexts["error_info"]["reason"]; got != "ACCOUNT_LOCKED" {
+		t.Errorf("Expected error info reason in context, got %v", got)
+	}
+	if got := event.Contexts["retry_info"]["retry_delay"]; got != "5s" {
+		t.Errorf("Expected retry delay in context, got %v", got)
+	}
+	if event.Tags["error_info.reason"] != "ACCOUNT_LOCKED" || event.Tags["error_info.domain"] != "accounts.example.com" {
+		t.Errorf("Expected error info tags, got %v", event.Tags)
+	}
+
+	unknown, _ := event.Contexts[unknownDetailsContext]["details"].([]interface{})
+	if len(unknown) != 1 {
+		t.Fatalf("Expected 1 unknown detail, got %v", event.Contexts[unknownDetailsContext])
+	}
+	if got := unknown[0].(sentry.Context)["@type"]; got != "type.googleapis.com/google.protobuf.StringValue" {
+		t.Errorf("Expected the type of the unknown detail, got %v", got)
+	}
+
+	if len(event.Exception) != 2 {
+		t.Fatalf("Expected the debug info exception before the error, got %v", event.Exception)
+	}
+	debug := event.Exception[0]
+	if debug.Type != "DebugInfo" || debug.Value != "lock held" || debug.Stacktrace == nil {
+		t.Fatalf("Expected debug info exception, got %+v", debug)
+	}
+	frames := debug.Stacktrace.Frames
+	if len(frames) != 3 || frames[0].Function != "main.main" || frames[2].Function != "accounts.lock" {
+		t.Errorf("Expected frames from the outermost call, got %+v", frames)
+	}
+}
+
+func TestAddStatusDetails_Repeated(t *testing.T) {
+	err := testStatusWithDetails(t,
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: "project:1"}}},
+		&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{{Subject: "project:2"}}},
+	)
+	event := addStatusDetails(&sentry.Event{}, &sentry.EventHint{OriginalException: err})
+
+	if _, ok := event.Contexts["quota_failure"]; !ok {
+		t.Error("Expected first quota failure context")
+	}
+	if _, ok := event.Contexts["quota_failure_2"]; !ok {
+		t.Errorf("Expected second quota failure context, got %v", event.Contexts)
+	}
+}
+
+func TestAddStatusDetails_WithoutDetails(t *testing.T) {
+	event := addStatusDetails(&sentry.Event{}, &sentry.EventHint{OriginalException: status.Error(codes.Internal, "boom")})
+	if len(event.Contexts) != 0 || len(event.Tags) != 0 {
+		t.Errorf("Expected event to be left unchanged, got %+v", event)
+	}
+}
+
+func TestUnaryServerInterceptor_StatusDetails(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryServerInterceptor()
+	handler := &mockUnaryHandler{err: testStatusWithDetails(t, &errdetails.ErrorInfo{Reason: "ACCOUNT_LOCKED", Domain: "accounts.example.com"})}
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].Tags["error_info.reason"] != "ACCOUNT_LOCKED" {
+		t.Errorf("Expected error info reason tag, got %v", events[0].Tags)
+	}
+	if _, ok := events[0].Contexts["error_info"]; !ok {
+		t.Errorf("Expected error info context, got %v", events[0].Contexts)
+	}
+}
+
+func TestUnaryClientInterceptor_StatusDetails(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryClientInterceptor()
+	invoker := &mockUnaryInvoker{err: testStatusWithDetails(t, &errdetails.RetryInfo{RetryDelay: durationpb.New(time.Second)})}
+	_ = interceptor(ctx, "/test.Service/Method", "request", nil, nil, invoker.invoke)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if got := events[0].Contexts["retry_info"]["retry_delay"]; got != "1s" {
+		t.Errorf("Expected retry info context, got %v", events[0].Contexts)
+	}
+}
+
+func TestInterceptors_NestedStatusDetails(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	server := UnaryServerInterceptor()
+	client := UnaryClientInterceptor()
+	downstream := &mockUnaryInvoker{err: testStatusWithDetails(t,
+		&errdetails.ErrorInfo{Reason: "ACCOUNT_LOCKED", Domain: "accounts.example.com"},
+		&errdetails.DebugInfo{Detail: "lock held", StackEntries: []string{"accounts.lock", "main.main"}},
+	)}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, client(ctx, "/down.Service/Method", "request", nil, nil, downstream.invoke)
+	}
+	_, _ = server(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/up.Service/Method"}, handler)
+
+	events := transport.Errors()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	for _, event := range events {
+		if _, ok := event.Contexts["error_info_2"]; ok {
+			t.Errorf("Expected the error info to be decoded once, got %v", event.Contexts)
+		}
+		if _, ok := event.Contexts["debug_info_2"]; ok {
+			t.Errorf("Expected the debug info to be decoded once, got %v", event.Contexts)
+		}
+		if len(event.Exception) != 2 {
+			t.Errorf("Expected the error and a single debug info exception, got %d exceptions", len(event.Exception))
+		}
+	}
+}
+
+func testStatusTrailer(t *testing.T, err error) metadata.MD {
+	t.Helper()
+	data, marshalErr := proto.Marshal(status.Convert(err).Proto())
+	if marshalErr != nil {
+		t.Fatalf("Failed to marshal status: %v", marshalErr)
+	}
+	return metadata.Pairs(statusDetailsKey, string(data))
+}
+
+func TestStatusFromTrailer(t *testing.T) {
+	trailer := testStatusTrailer(t, testStatusWithDetails(t, &errdetails.ErrorInfo{Reason: "ACCOUNT_LOCKED"}))
+
+	s := statusFromTrailer(trailer)
+	if s == nil || codes.Code(s.GetCode()) != codes.FailedPrecondition || len(s.GetDetails()) != 1 {
+		t.Errorf("Expected the full status, got %v", s)
+	}
+	if s := statusFromTrailer(metadata.Pairs(statusDetailsKey, "not a status")); s != nil {
+		t.Errorf("Expected no status from an invalid trailer, got %v", s)
+	}
+	if s := statusFromTrailer(nil); s != nil {
+		t.Errorf("Expected no status without trailer, got %v", s)
+	}
+}
+
+func TestUnaryClientInterceptor_DownstreamStatus(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	downstream := testStatusWithDetails(t, &errdetails.ErrorInfo{Reason: "ACCOUNT_LOCKED", Domain: "accounts.example.com"})
+	interceptor := UnaryClientInterceptor()
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			if opt, ok := opt.(grpc.TrailerCallOption); ok {
+				*opt.TrailerAddr = testStatusTrailer(t, downstream)
+			}
+		}
+		// Another interceptor replaced the status error, losing its details.
+		return fmt.Errorf("calling accounts: %s", downstream)
+	}
+	_ = interceptor(ctx, "/test.Service/Method", "request", nil, nil, invoker)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	got := events[0].Contexts[downstreamStatusContext]
+	if got["code"] != "FailedPrecondition" || got["message"] != "account is locked" {
+		t.Errorf("Expected the downstream status, got %v", got)
+	}
+	if details, _ := got["details"].([]interface{}); len(details) != 1 {
+		t.Errorf("Expected the downstream status details, got %v", got["details"])
+	}
+	if events[0].Tags["error_info.reason"] != "ACCOUNT_LOCKED" {
+		t.Errorf("Expected details decoded from the trailer, got %v", events[0].Tags)
+	}
+}
+
+// trailerClientStream is a scripted stream returning a trailer once the call failed.
+type trailerClientStream struct {
+	scriptedClientStream
+	trailer metadata.MD
+}
+
+func (s *trailerClientStream) Trailer() metadata.MD { return s.trailer }
+
+func TestClientStream_DownstreamStatus(t *testing.T) {
+	downstream := testStatusWithDetails(t, &errdetails.DebugInfo{Detail: "lock held", StackEntries: []string{"accounts.lock"}})
+	cs := &trailerClientStream{
+		scriptedClientStream: scriptedClientStream{recvErrs: []error{downstream}},
+		trailer:              testStatusTrailer(t, downstream),
+	}
+	stream, transport := startClientStream(t, context.Background(), &grpc.StreamDesc{ServerStreams: true}, cs)
+	_ = stream.RecvMsg(nil)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if _, ok := events[0].Contexts[downstreamStatusContext]; !ok {
+		t.Errorf("Expected the downstream status context, got %v", events[0].Contexts)
+	}
+	if len(events[0].Exception) != 2 {
+		t.Errorf("Expected the debug info to be decoded only once, got %d exceptions", len(events[0].Exception))
+	}
+}
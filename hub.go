@@ -0,0 +1,112 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/getsentry/sentry-go"
+)
+
+// HubFactory returns the hub an RPC reports through. Returning nil falls back to the hub found on the context.
+type HubFactory func(ctx context.Context) *sentry.Hub
+
+// callEventsContext is the scope context through which processCallEvents finds the call an event is captured for.
+// It never leaves the process.
+const callEventsContext = "grpc_sentry.call"
+
+// callEventsKey is the context key of the callEvents of the innermost call, which become the parent of the events of
+// a call nested in it.
+type callEventsKey struct{}
+
+// callEvents holds the event processors shaping the events captured during a single RPC. They are kept with the
+// call rather than added to its scope: the clone of a scope shares the processors of the scope it was cloned from,
+// so adding one to the scope of a call would race with every other call cloned from the same hub. The hub of a call
+// nested in another one, e.g. a client call made by a server handler, also inherits the scope of the outer call;
+// only the processors of the innermost call shape the events of the nested call, while the processors configured
+// for the calls enclosing it still run for them.
+type callEvents struct {
+	mu         sync.Mutex
+	processors []sentry.EventProcessor
+
+	// configured are the processors set with WithEventProcessors, which run last.
+	configured []sentry.EventProcessor
+
+	// parent is the call this one is nested in, if any.
+	parent *callEvents
+}
+
+// addEventProcessor adds a processor run on the events captured during the call, after the ones added before.
+func (c *callEvents) addEventProcessor(processor sentry.EventProcessor) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.processors = append(c.processors, processor)
+}
+
+// process runs the processors of the call on the event, followed by the processors configured for each of the calls
+// enclosing it from the innermost to the outermost, stopping as soon as one of them drops the event.
+func (c *callEvents) process(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	c.mu.Lock()
+	processors := append(c.processors[:len(c.processors):len(c.processors)], c.configured...)
+	c.mu.Unlock()
+	for parent := c.parent; parent != nil; parent = parent.parent {
+		processors = append(processors, parent.configured...)
+	}
+
+	for _, processor := range processors {
+		if event = processor(event, hint); event == nil {
+			return nil
+		}
+	}
+	return event
+}
+
+// The processors of a call run from a global event processor, registered while the package is initialized since the
+// global processors of the SDK are not safe to add to concurrently.
+func init() {
+	sentry.AddGlobalEventProcessor(processCallEvents)
+}
+
+// processCallEvents runs the processors of the call found in the callEventsContext of the event, which the hub of
+// each nested call replaces with its own. Events captured outside of a call are left unchanged.
+func processCallEvents(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+	c, _ := event.Contexts[callEventsContext]["events"].(*callEvents)
+	delete(event.Contexts, callEventsContext)
+	if c == nil {
+		return event
+	}
+	return c.process(event, hint)
+}
+
+// hubForCall returns a hub dedicated to a single RPC along with the processors of its events and a context carrying
+// the hub. The hub is cloned from the one configured with WithHubFactory or WithHub, or else from the one found on
+// the context or the current hub, so that tags, extras and spans set while handling the call live in their own scope
+// and are discarded when the call ends instead of leaking into other calls. A client configured with WithClient
+// replaces the one of the hub, and the processors configured with WithEventProcessors run for the events of the
+// call.
+func hubForCall(ctx context.Context, o *options) (*sentry.Hub, *callEvents, context.Context) {
+	var hub *sentry.Hub
+	if o.HubFactory != nil {
+		hub = o.HubFactory(ctx)
+	}
+	if hub == nil {
+		hub = o.Hub
+	}
+	if hub == nil {
+		hub = sentry.GetHubFromContext(ctx)
+	}
+	if hub == nil {
+		hub = sentry.CurrentHub()
+	}
+	hub = hub.Clone()
+	if o.Client != nil {
+		hub.BindClient(o.Client)
+	}
+
+	parent, _ := ctx.Value(callEventsKey{}).(*callEvents)
+	events := &callEvents{configured: o.EventProcessors, parent: parent}
+	hub.Scope().SetContext(callEventsContext, sentry.Context{"events": events})
+	ctx = context.WithValue(ctx, callEventsKey{}, events)
+	return hub, events, sentry.SetHubOnContext(ctx, hub)
+}
@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHubForCall(t *testing.T) {
+	contextHub, _ := newRecordingHub(t)
+	configuredHub, _ := newRecordingHub(t)
+	factoryHub, _ := newRecordingHub(t)
+	client := configuredHub.Client()
+	ctx := sentry.SetHubOnContext(context.Background(), contextHub)
+
+	tests := []struct {
+		name     string
+		options  []Option
+		expected *sentry.Client
+	}{
+		{"hub from the context", nil, contextHub.Client()},
+		{"configured hub", []Option{WithHub(configuredHub)}, configuredHub.Client()},
+		{"configured client", []Option{WithClient(client)}, client},
+		{
+			name:     "hub factory",
+			options:  []Option{WithHub(configuredHub), WithHubFactory(func(context.Context) *sentry.Hub { return factoryHub })},
+			expected: factoryHub.Client(),
+		},
+		{
+			name:     "hub factory falling back",
+			options:  []Option{WithHub(configuredHub), WithHubFactory(func(context.Context) *sentry.Hub { return nil })},
+			expected: configuredHub.Client(),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, _, callCtx := hubForCall(ctx, newConfig(tt.options))
+			if hub.Client() != tt.expected {
+				t.Error("Expected the call to report through the configured client")
+			}
+			if hub == contextHub || hub == configuredHub || hub == factoryHub {
+				t.Error("Expected the hub of the call to be a clone")
+			}
+			if sentry.GetHubFromContext(callCtx) != hub {
+				t.Error("Expected the hub of the call on the returned context")
+			}
+		})
+	}
+}
+
+func TestWithClient_KeepsContextScope(t *testing.T) {
+	contextHub, contextTransport := newRecordingHub(t)
+	contextHub.Scope().SetTag("upstream", "http")
+	configuredHub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), contextHub)
+
+	interceptor := UnaryServerInterceptor(WithClient(configuredHub.Client()))
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "boom")}
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+	if len(contextTransport.Errors()) != 0 {
+		t.Error("Expected no event through the client of the context hub")
+	}
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Tags["upstream"] != "http" {
+		t.Errorf("Expected 1 event with the scope of the context hub, got %v", events)
+	}
+}
+
+func TestInterceptors_SeparateHubsDoNotMixEvents(t *testing.T) {
+	billingHub, billingTransport := newRecordingHub(t)
+	searchHub, searchTransport := newRecordingHub(t)
+	globalHub, globalTransport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), globalHub)
+
+	billing := UnaryServerInterceptor(WithHub(billingHub))
+	search := StreamServerInterceptor(WithClient(searchHub.Client()))
+	billingInfo := &grpc.UnaryServerInfo{FullMethod: "/billing.Invoices/Get"}
+	searchInfo := &grpc.StreamServerInfo{FullMethod: "/search.Search/Query", IsServerStream: true}
+
+	const calls = 20
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			handler := &mockUnaryHandler{err: status.Error(codes.Internal, fmt.Sprintf("billing %d", i))}
+			_, _ = billing(ctx, "request", billingInfo, handler.handle)
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+			handler := &mockStreamHandler{err: status.Error(codes.Internal, fmt.Sprintf("search %d", i))}
+			_ = search(nil, &mockServerStream{ctx: ctx}, searchInfo, handler.handle)
+		}(i)
+	}
+	wg.Wait()
+
+	if got := len(globalTransport.Errors()) + len(globalTransport.Transactions()); got != 0 {
+		t.Errorf("Expected nothing sent through the global hub, got %d events", got)
+	}
+	for name, tt := range map[string]struct {
+		transport *recordingTransport
+		method    string
+	}{
+		"billing": {billingTransport, billingInfo.FullMethod},
+		"search":  {searchTransport, searchInfo.FullMethod},
+	} {
+		events := tt.transport.Errors()
+		if len(events) != calls {
+			t.Errorf("Expected %d %s events, got %d", calls, name, len(events))
+		}
+		for _, event := range events {
+			if event.Request == nil || event.Request.URL != tt.method {
+				t.Errorf("Expected only %s events, got %+v", tt.method, event.Request)
+			}
+		}
+		if len(tt.transport.Transactions()) != calls {
+			t.Errorf("Expected %d %s transactions, got %d", calls, name, len(tt.transport.Transactions()))
+		}
+	}
+}
+
+func TestInterceptors_NestedCallEvents(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	server := UnaryServerInterceptor(WithCaptureRequestBody(true))
+	client := UnaryClientInterceptor(WithCaptureRequestBody(false))
+	downstream := &mockUnaryInvoker{err: status.Error(codes.NotFound, "not found")}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		err := client(ctx, "/down.Service/Method", "client-request", nil, nil, downstream.invoke)
+		return nil, err
+	}
+	_, _ = server(ctx, "server-secret-request", &grpc.UnaryServerInfo{FullMethod: "/up.Service/Method"}, handler)
+
+	events := transport.Errors()
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+	clientEvent, serverEvent := events[0], events[1]
+
+	if clientEvent.Request == nil || clientEvent.Request.URL != "/down.Service/Method" {
+		t.Errorf("Expected the client event to describe the client call, got %+v", clientEvent.Request)
+	} else if clientEvent.Request.Data != "" {
+		t.Errorf("Expected no request data on the client event, got %q", clientEvent.Request.Data)
+	}
+	if got := clientEvent.Contexts["grpc"]["service"]; got != "down.Service" {
+		t.Errorf("Expected the grpc context of the client call, got %v", got)
+	}
+	for _, event := range append(events, transport.Transactions()...) {
+		if _, ok := event.Contexts[callEventsContext]; ok {
+			t.Errorf("Expected the call of the event to be left out of its contexts, got %v", event.Contexts)
+		}
+	}
+
+	if serverEvent.Request == nil || serverEvent.Request.URL != "/up.Service/Method" ||
+		serverEvent.Request.Data != "server-secret-request" {
+		t.Errorf("Expected the server event to describe the server call, got %+v", serverEvent.Request)
+	}
+}
@@ -0,0 +1,154 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"path"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// defaultDeniedMetadata lists the metadata keys that usually carry credentials and are therefore never captured
+// unless WithDefaultDeniedMetadata(false) is used.
+var defaultDeniedMetadata = []string{
+	"authorization",
+	"proxy-authorization",
+	"cookie",
+	"set-cookie",
+	"x-api-key",
+	"*token*",
+	"*secret*",
+	"*password*",
+	"*session*",
+}
+
+// metadataFilter decides which metadata keys may leave the process. Keys and patterns are compared in lower case,
+// as gRPC metadata keys are case-insensitive.
+type metadataFilter struct {
+	allow []string
+	deny  []string
+}
+
+// newMetadataFilter returns the filter configured by the options. Unless CaptureMetadata is enabled only the fixed
+// requestHeaders are allowed, so that events still describe the client without carrying arbitrary metadata.
+func newMetadataFilter(o *options) *metadataFilter {
+	f := &metadataFilter{allow: o.AllowedMetadata, deny: o.DeniedMetadata}
+	if !o.CaptureMetadata {
+		f.allow = requestHeaders
+	}
+	if o.DenyDefaultMetadata {
+		f.deny = append(defaultDeniedMetadata[:len(defaultDeniedMetadata):len(defaultDeniedMetadata)], f.deny...)
+	}
+	return f
+}
+
+// allowed reports whether the metadata key may be captured. Binary metadata is never captured.
+func (f *metadataFilter) allowed(key string) bool {
+	key = strings.ToLower(key)
+	if strings.HasSuffix(key, "-bin") {
+		return false
+	}
+	if matchesAnyKey(f.deny, key) {
+		return false
+	}
+	return len(f.allow) == 0 || matchesAnyKey(f.allow, key)
+}
+
+// filter returns the allowed metadata, joining the values of repeated keys with commas.
+func (f *metadataFilter) filter(mds ...metadata.MD) map[string]string {
+	var captured map[string]string
+	for _, md := range mds {
+		for key, values := range md {
+			if len(values) == 0 || !f.allowed(key) {
+				continue
+			}
+			if captured == nil {
+				captured = make(map[string]string)
+			}
+			key = strings.ToLower(key)
+			if existing, ok := captured[key]; ok {
+				values = append([]string{existing}, values...)
+			}
+			captured[key] = strings.Join(values, ",")
+		}
+	}
+	return captured
+}
+
+// tags returns the metadata promoted to tags by MetadataTags, provided the keys are allowed to leave the process at
+// all. Promoted keys do not need to be part of AllowedMetadata.
+func (f *metadataFilter) tags(o *options, mds ...metadata.MD) map[string]string {
+	if len(o.MetadataTags) == 0 {
+		return nil
+	}
+	promoted := &metadataFilter{allow: o.MetadataTags, deny: f.deny}
+	return promoted.filter(mds...)
+}
+
+func matchesAnyKey(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// recordMetadata records the captured metadata as data of the span and promotes the configured keys to tags of both
+// the span and the scope of the call.
+func recordMetadata(scope *sentry.Scope, span *sentry.Span, o *options, md metadata.MD) {
+	f := newMetadataFilter(o)
+	if o.CaptureMetadata {
+		for key, value := range f.filter(md) {
+			span.SetData("grpc.metadata."+key, value)
+		}
+	}
+	for key, value := range f.tags(o, md) {
+		span.SetTag(key, value)
+		scope.SetTag(key, value)
+	}
+}
+
+// capturesResponseMetadata reports whether client calls need to retrieve their response header and trailer.
+func capturesResponseMetadata(o *options) bool {
+	return o.CaptureMetadata || len(o.MetadataTags) > 0
+}
+
+// attachResponseMetadata attaches the allowed response header and trailer metadata of a client call to the events
+// captured during the call as headers of their response context. Promoted keys are set as tags of the scope.
+func attachResponseMetadata(scope *sentry.Scope, events *callEvents, o *options, header, trailer metadata.MD) {
+	f := newMetadataFilter(o)
+	for key, value := range f.tags(o, header, trailer) {
+		scope.SetTag(key, value)
+	}
+	if !o.CaptureMetadata {
+		return
+	}
+	headers := f.filter(header, trailer)
+	if len(headers) == 0 {
+		return
+	}
+
+	events.addEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		if event.Type == "transaction" {
+			return event
+		}
+		responseContext(event)["headers"] = headers
+		return event
+	})
+}
+
+// responseContext returns the response context of the event, creating it when needed, so that the response body and
+// metadata can be attached independently.
+func responseContext(event *sentry.Event) sentry.Context {
+	if event.Contexts == nil {
+		event.Contexts = make(map[string]sentry.Context)
+	}
+	c, ok := event.Contexts["response"]
+	if !ok {
+		c = sentry.Context{"type": "response"}
+		event.Contexts["response"] = c
+	}
+	return c
+}
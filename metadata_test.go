@@ -0,0 +1,197 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestMetadataFilter_Allowed(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		key      string
+		expected bool
+	}{
+		{"fixed headers when capture is disabled", nil, "user-agent", true},
+		{"other keys when capture is disabled", nil, "x-request-id", false},
+		{"any key when nothing is allowed", []Option{WithCaptureMetadata(true)}, "x-request-id", true},
+		{"authorization is denied", []Option{WithCaptureMetadata(true)}, "Authorization", false},
+		{"cookies are denied", []Option{WithCaptureMetadata(true)}, "cookie", false},
+		{"token patterns are denied", []Option{WithCaptureMetadata(true)}, "x-refresh-token", false},
+		{"binary metadata is never captured", []Option{WithCaptureMetadata(true)}, "x-trace-bin", false},
+		{"allowlist matches", []Option{WithCaptureMetadata(true), WithAllowedMetadata("x-tenant-*")}, "x-tenant-id", true},
+		{"allowlist excludes", []Option{WithCaptureMetadata(true), WithAllowedMetadata("x-tenant-*")}, "x-request-id", false},
+		{"denylist wins over allowlist", []Option{WithCaptureMetadata(true), WithAllowedMetadata("x-*"), WithDeniedMetadata("x-internal-*")}, "x-internal-route", false},
+		{"default denylist disabled", []Option{WithCaptureMetadata(true), WithDefaultDeniedMetadata(false)}, "authorization", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newMetadataFilter(newConfig(tt.options))
+			if got := f.allowed(tt.key); got != tt.expected {
+				t.Errorf("Expected %s allowed to be %v, got %v", tt.key, tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestMetadataFilter_Filter(t *testing.T) {
+	f := newMetadataFilter(newConfig([]Option{WithCaptureMetadata(true)}))
+	header := metadata.Pairs("x-request-id", "abc", "set-cookie", "session=1")
+	trailer := metadata.Pairs("x-request-id", "def", "x-retry-after", "5")
+
+	got := f.filter(header, trailer)
+	expected := map[string]string{"x-request-id": "abc,def", "x-retry-after": "5"}
+	if len(got) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, got)
+	}
+	for k, v := range expected {
+		if got[k] != v {
+			t.Errorf("Expected %s to be %s, got %s", k, v, got[k])
+		}
+	}
+}
+
+func TestUnaryServerInterceptor_Metadata(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(
+		"x-request-id", "req-1",
+		"x-tenant-id", "acme",
+		"authorization", "Bearer secret",
+		"cookie", "session=secret",
+	))
+
+	interceptor := UnaryServerInterceptor(
+		WithCaptureMetadata(true),
+		WithMetadataTags("x-tenant-id", "authorization"),
+	)
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "boom")}
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Request == nil {
+		t.Fatalf("Expected 1 event with a request, got %v", events)
+	}
+	headers := events[0].Request.Headers
+	if headers["x-request-id"] != "req-1" || headers["x-tenant-id"] != "acme" {
+		t.Errorf("Expected allowed metadata as headers, got %v", headers)
+	}
+	if _, ok := headers["authorization"]; ok {
+		t.Errorf("Expected authorization to be denied, got %v", headers)
+	}
+	if _, ok := headers["cookie"]; ok {
+		t.Errorf("Expected cookie to be denied, got %v", headers)
+	}
+	if events[0].Tags["x-tenant-id"] != "acme" {
+		t.Errorf("Expected x-tenant-id tag, got %v", events[0].Tags)
+	}
+	if _, ok := events[0].Tags["authorization"]; ok {
+		t.Errorf("Expected authorization not to be promoted to a tag, got %v", events[0].Tags)
+	}
+
+	transactions := transport.Transactions()
+	if len(transactions) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(transactions))
+	}
+	if transactions[0].Tags["x-tenant-id"] != "acme" {
+		t.Errorf("Expected x-tenant-id tag on the transaction, got %v", transactions[0].Tags)
+	}
+	data, _ := transactions[0].Contexts["trace"]["data"].(map[string]interface{})
+	if data["grpc.metadata.x-request-id"] != "req-1" {
+		t.Errorf("Expected metadata as transaction data, got %v", data)
+	}
+	if _, ok := data["grpc.metadata.authorization"]; ok {
+		t.Errorf("Expected authorization not to be transaction data, got %v", data)
+	}
+}
+
+func TestUnaryClientInterceptor_Metadata(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "req-1", "authorization", "Bearer secret")
+
+	interceptor := UnaryClientInterceptor(WithCaptureMetadata(true), WithMetadataTags("x-served-by"))
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		for _, opt := range opts {
+			switch opt := opt.(type) {
+			case grpc.HeaderCallOption:
+				*opt.HeaderAddr = metadata.Pairs("x-served-by", "backend-2", "set-cookie", "session=secret")
+			case grpc.TrailerCallOption:
+				*opt.TrailerAddr = metadata.Pairs("x-retry-after", "5")
+			}
+		}
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	_ = interceptor(ctx, "/test.Service/Method", "request", nil, nil, invoker)
+
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Request == nil {
+		t.Fatalf("Expected 1 event with a request, got %v", events)
+	}
+	if headers := events[0].Request.Headers; headers["x-request-id"] != "req-1" || headers["authorization"] != "" {
+		t.Errorf("Expected outgoing metadata without authorization, got %v", headers)
+	}
+	headers, _ := events[0].Contexts["response"]["headers"].(map[string]string)
+	if headers["x-served-by"] != "backend-2" || headers["x-retry-after"] != "5" {
+		t.Errorf("Expected response header and trailer, got %v", events[0].Contexts["response"])
+	}
+	if _, ok := headers["set-cookie"]; ok {
+		t.Errorf("Expected set-cookie to be denied, got %v", headers)
+	}
+	if events[0].Tags["x-served-by"] != "backend-2" {
+		t.Errorf("Expected x-served-by tag, got %v", events[0].Tags)
+	}
+}
+
+func TestUnaryClientInterceptor_MetadataWithoutTraceHeaders(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", "req-1")
+
+	interceptor := UnaryClientInterceptor(WithCaptureMetadata(true))
+	var sent metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		sent, _ = metadata.FromOutgoingContext(ctx)
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+	_ = interceptor(ctx, "/test.Service/Method", "request", nil, nil, invoker)
+
+	if len(sent.Get(sentry.SentryTraceHeader)) != 1 || len(sent.Get(sentry.SentryBaggageHeader)) != 1 {
+		t.Fatalf("Expected the trace headers to be sent once, got %v", sent)
+	}
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Request == nil {
+		t.Fatalf("Expected 1 event with a request, got %v", events)
+	}
+	headers := events[0].Request.Headers
+	if headers["x-request-id"] != "req-1" {
+		t.Errorf("Expected the metadata of the caller, got %v", headers)
+	}
+	for _, key := range []string{sentry.SentryTraceHeader, sentry.SentryBaggageHeader} {
+		if _, ok := headers[key]; ok {
+			t.Errorf("Expected %s not to be captured, got %v", key, headers)
+		}
+	}
+
+	transactions := transport.Transactions()
+	if len(transactions) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(transactions))
+	}
+	data, _ := transactions[0].Contexts["trace"]["data"].(map[string]interface{})
+	if data["grpc.metadata.x-request-id"] != "req-1" {
+		t.Errorf("Expected the metadata of the caller as span data, got %v", data)
+	}
+	for _, key := range []string{sentry.SentryTraceHeader, sentry.SentryBaggageHeader} {
+		if _, ok := data["grpc.metadata."+key]; ok {
+			t.Errorf("Expected %s not to be span data, got %v", key, data)
+		}
+	}
+}
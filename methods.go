@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultIgnoredMethods are the services that are not instrumented unless WithDefaultIgnoredMethods(false) is used.
+// Health checks and reflection are called often and rarely carry anything worth reporting.
+var defaultIgnoredMethods = []string{
+	"grpc.health.v1.Health",
+	"grpc.reflection.v1.ServerReflection",
+	"grpc.reflection.v1alpha.ServerReflection",
+}
+
+// methodPattern matches full gRPC method names of the form "/package.Service/Method". A pattern is one of:
+//
+//   - a glob such as "/billing.*" or "*/Check", where '*' matches any sequence of characters (including '/') and
+//     '?' matches a single character;
+//   - a service name such as "grpc.health.v1.Health" or "/grpc.health.v1.Health/", matching all of its methods;
+//   - a full method name such as "/grpc.health.v1.Health/Check", matching exactly.
+//
+// The leading slash of a full method name or glob is optional.
+type methodPattern struct {
+	exact  string
+	prefix string
+	glob   *regexp.Regexp
+}
+
+func compileMethodPattern(pattern string) methodPattern {
+	if strings.ContainsAny(pattern, "*?") {
+		if !strings.HasPrefix(pattern, "/") && !strings.HasPrefix(pattern, "*") {
+			pattern = "/" + pattern
+		}
+		expr := regexp.QuoteMeta(pattern)
+		expr = strings.ReplaceAll(expr, `\*`, ".*")
+		expr = strings.ReplaceAll(expr, `\?`, ".")
+		return methodPattern{glob: regexp.MustCompile("^" + expr + "$")}
+	}
+
+	pattern = strings.TrimPrefix(pattern, "/")
+	if strings.HasSuffix(pattern, "/") || !strings.Contains(pattern, "/") {
+		return methodPattern{prefix: "/" + strings.TrimSuffix(pattern, "/") + "/"}
+	}
+	return methodPattern{exact: "/" + pattern}
+}
+
+func (p methodPattern) match(fullMethod string) bool {
+	switch {
+	case p.glob != nil:
+		return p.glob.MatchString(fullMethod)
+	case p.prefix != "":
+		return strings.HasPrefix(fullMethod, p.prefix)
+	default:
+		return fullMethod == p.exact
+	}
+}
+
+// methodMatcher matches a full method name against any of a list of patterns.
+type methodMatcher []methodPattern
+
+func newMethodMatcher(patterns []string) methodMatcher {
+	m := make(methodMatcher, 0, len(patterns))
+	for _, p := range patterns {
+		m = append(m, compileMethodPattern(p))
+	}
+	return m
+}
+
+func (m methodMatcher) match(fullMethod string) bool {
+	for _, p := range m {
+		if p.match(fullMethod) {
+			return true
+		}
+	}
+	return false
+}
+
+// methodOverride holds options registered with WithMethodOptions for the methods matching a pattern.
+type methodOverride struct {
+	pattern methodPattern
+	opts    []Option
+}
+
+// maxCachedMethods bounds the number of methods for which decisions are cached. The methods served or called by a
+// process are few, but grpc runs the stream interceptors for the calls handled by an UnknownServiceHandler, so
+// clients can make up any number of method names.
+const maxCachedMethods = 1024
+
+// methodFilter decides which methods are instrumented. Decisions are cached per method, up to maxCachedMethods.
+type methodFilter struct {
+	ignore methodMatcher
+	only   methodMatcher
+
+	decisions sync.Map // full method name -> bool
+	cached    atomic.Int64
+}
+
+func newMethodFilter(o *options) *methodFilter {
+	ignore := o.IgnoreMethods
+	if o.IgnoreDefaultMethods {
+		ignore = append(ignore[:len(ignore):len(ignore)], defaultIgnoredMethods...)
+	}
+	return &methodFilter{
+		ignore: newMethodMatcher(ignore),
+		only:   newMethodMatcher(o.OnlyMethods),
+	}
+}
+
+// instrumented reports whether calls to fullMethod should be reported to Sentry. Ignored methods take precedence
+// over the ones explicitly allowed.
+func (f *methodFilter) instrumented(fullMethod string) bool {
+	if d, ok := f.decisions.Load(fullMethod); ok {
+		return d.(bool)
+	}
+
+	d := !f.ignore.match(fullMethod) && (len(f.only) == 0 || f.only.match(fullMethod))
+	if f.cached.Load() < maxCachedMethods {
+		if _, loaded := f.decisions.LoadOrStore(fullMethod, d); !loaded {
+			f.cached.Add(1)
+		}
+	}
+	return d
+}
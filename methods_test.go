@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestMethodPattern_Match(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    string
+		fullMethod string
+		want       bool
+	}{
+		{"exact match", "/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Check", true},
+		{"exact without leading slash", "grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Check", true},
+		{"exact mismatch", "/grpc.health.v1.Health/Check", "/grpc.health.v1.Health/Watch", false},
+		{"service name", "grpc.health.v1.Health", "/grpc.health.v1.Health/Watch", true},
+		{"service name with slashes", "/grpc.health.v1.Health/", "/grpc.health.v1.Health/Check", true},
+		{"service name is not a prefix of another service", "grpc.health.v1.Health", "/grpc.health.v1.HealthX/Check", false},
+		{"glob across services", "/billing.*", "/billing.v1.Invoices/Create", true},
+		{"glob without leading slash", "billing.*", "/billing.v1.Invoices/Create", true},
+		{"glob mismatch", "/billing.*", "/search.Search/Query", false},
+		{"glob on method", "*/Check", "/grpc.health.v1.Health/Check", true},
+		{"single character glob", "/test.Service/Get?", "/test.Service/GetX", true},
+		{"single character glob mismatch", "/test.Service/Get?", "/test.Service/Get", false},
+		{"glob metacharacters are literal", "/test.Service/*", "/testXService/Get", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compileMethodPattern(tt.pattern).match(tt.fullMethod); got != tt.want {
+				t.Errorf("Expected pattern %q to match %q: %v, got %v", tt.pattern, tt.fullMethod, tt.want, got)
+			}
+		})
+	}
+}
+
+func TestMethodFilter_Instrumented(t *testing.T) {
+	tests := []struct {
+		name       string
+		options    []Option
+		fullMethod string
+		want       bool
+	}{
+		{
+			name:       "default instruments everything",
+			options:    []Option{},
+			fullMethod: "/test.Service/Method",
+			want:       true,
+		},
+		{
+			name:       "default ignores health checks",
+			options:    []Option{},
+			fullMethod: "/grpc.health.v1.Health/Check",
+			want:       false,
+		},
+		{
+			name:       "default ignores reflection",
+			options:    []Option{},
+			fullMethod: "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo",
+			want:       false,
+		},
+		{
+			name:       "default ignores can be turned off",
+			options:    []Option{WithDefaultIgnoredMethods(false)},
+			fullMethod: "/grpc.health.v1.Health/Check",
+			want:       true,
+		},
+		{
+			name:       "ignored method",
+			options:    []Option{WithIgnoreMethods("/test.Service/Method")},
+			fullMethod: "/test.Service/Method",
+			want:       false,
+		},
+		{
+			name:       "ignore is cumulative",
+			options:    []Option{WithIgnoreMethods("a.Service"), WithIgnoreMethods("b.Service")},
+			fullMethod: "/a.Service/Method",
+			want:       false,
+		},
+		{
+			name:       "only matching method",
+			options:    []Option{WithOnlyMethods("/billing.*")},
+			fullMethod: "/billing.Invoices/Create",
+			want:       true,
+		},
+		{
+			name:       "only non-matching method",
+			options:    []Option{WithOnlyMethods("/billing.*")},
+			fullMethod: "/search.Search/Query",
+			want:       false,
+		},
+		{
+			name:       "ignore takes precedence over only",
+			options:    []Option{WithOnlyMethods("/billing.*"), WithIgnoreMethods("/billing.Invoices/List")},
+			fullMethod: "/billing.Invoices/List",
+			want:       false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := newConfig(tt.options)
+			// Check twice to cover cached decisions.
+			for i := 0; i < 2; i++ {
+				if got := o.methods.instrumented(tt.fullMethod); got != tt.want {
+					t.Errorf("Expected %q to be instrumented: %v, got %v", tt.fullMethod, tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestMethodFilter_CacheIsBounded(t *testing.T) {
+	o := newConfig([]Option{WithIgnoreMethods("/test.Service/Ignored")})
+	for i := 0; i < 2*maxCachedMethods; i++ {
+		o.methods.instrumented(fmt.Sprintf("/unknown.Service/Method%d", i))
+	}
+
+	var cached int
+	o.methods.decisions.Range(func(_, _ interface{}) bool {
+		cached++
+		return true
+	})
+	if cached != maxCachedMethods {
+		t.Errorf("Expected %d cached decisions, got %d", maxCachedMethods, cached)
+	}
+	if o.methods.instrumented("/test.Service/Ignored") {
+		t.Error("Expected decisions to be made once the cache is full")
+	}
+}
@@ -0,0 +1,30 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import "strings"
+
+// TransactionNamer returns the name of the transaction of an RPC handled by the server interceptors.
+type TransactionNamer func(fullMethod string) string
+
+// FullMethodName names transactions with the full method, e.g. "/billing.v1.Invoices/Get". This is the default.
+func FullMethodName(fullMethod string) string {
+	return fullMethod
+}
+
+// ServiceMethodName names transactions with the service and method without package, e.g. "Invoices/Get".
+func ServiceMethodName(fullMethod string) string {
+	service, method := splitMethod(fullMethod)
+	if i := strings.LastIndex(service, "."); i >= 0 {
+		service = service[i+1:]
+	}
+	if service == "" {
+		return method
+	}
+	return service + "/" + method
+}
+
+// MethodName names transactions with the method only, e.g. "Get".
+func MethodName(fullMethod string) string {
+	_, method := splitMethod(fullMethod)
+	return method
+}
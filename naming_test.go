@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+)
+
+func TestTransactionNamers(t *testing.T) {
+	tests := []struct {
+		fullMethod    string
+		full          string
+		serviceMethod string
+		method        string
+	}{
+		{"/billing.v1.Invoices/Get", "/billing.v1.Invoices/Get", "Invoices/Get", "Get"},
+		{"/Invoices/Get", "/Invoices/Get", "Invoices/Get", "Get"},
+		{"Get", "Get", "Get", "Get"},
+	}
+
+	for _, tt := range tests {
+		if got := FullMethodName(tt.fullMethod); got != tt.full {
+			t.Errorf("Expected FullMethodName(%q) to be %q, got %q", tt.fullMethod, tt.full, got)
+		}
+		if got := ServiceMethodName(tt.fullMethod); got != tt.serviceMethod {
+			t.Errorf("Expected ServiceMethodName(%q) to be %q, got %q", tt.fullMethod, tt.serviceMethod, got)
+		}
+		if got := MethodName(tt.fullMethod); got != tt.method {
+			t.Errorf("Expected MethodName(%q) to be %q, got %q", tt.fullMethod, tt.method, got)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor_TransactionNamer(t *testing.T) {
+	tests := []struct {
+		name           string
+		options        []Option
+		expectedName   string
+		expectedSource sentry.TransactionSource
+	}{
+		{
+			name:           "default",
+			options:        nil,
+			expectedName:   "/billing.v1.Invoices/Get",
+			expectedSource: sentry.SourceRoute,
+		},
+		{
+			name:           "service and method",
+			options:        []Option{WithTransactionNamer(ServiceMethodName, sentry.SourceRoute)},
+			expectedName:   "Invoices/Get",
+			expectedSource: sentry.SourceRoute,
+		},
+		{
+			name:           "method only",
+			options:        []Option{WithTransactionNamer(MethodName, sentry.SourceRoute)},
+			expectedName:   "Get",
+			expectedSource: sentry.SourceRoute,
+		},
+		{
+			name:           "custom",
+			options:        []Option{WithTransactionNamer(strings.ToUpper, sentry.SourceCustom)},
+			expectedName:   "/BILLING.V1.INVOICES/GET",
+			expectedSource: sentry.SourceCustom,
+		},
+		{
+			name:           "custom without source",
+			options:        []Option{WithTransactionNamer(strings.ToLower, "")},
+			expectedName:   "/billing.v1.invoices/get",
+			expectedSource: sentry.SourceCustom,
+		},
+		{
+			name:           "nil falls back to the default",
+			options:        []Option{WithTransactionNamer(nil, sentry.SourceCustom)},
+			expectedName:   "/billing.v1.Invoices/Get",
+			expectedSource: sentry.SourceRoute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, transport := newRecordingHub(t)
+			ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+			handler := &mockUnaryHandler{}
+			info := &grpc.UnaryServerInfo{FullMethod: "/billing.v1.Invoices/Get"}
+			_, _ = UnaryServerInterceptor(tt.options...)(ctx, "request", info, handler.handle)
+
+			transactions := transport.Transactions()
+			if len(transactions) != 1 {
+				t.Fatalf("Expected 1 transaction, got %d", len(transactions))
+			}
+			if transactions[0].Transaction != tt.expectedName {
+				t.Errorf("Expected transaction name %q, got %q", tt.expectedName, transactions[0].Transaction)
+			}
+			if source := transactions[0].TransactionInfo.Source; source != tt.expectedSource {
+				t.Errorf("Expected transaction source %s, got %s", tt.expectedSource, source)
+			}
+		})
+	}
+}
+
+func TestStreamServerInterceptor_TransactionNamer(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	handler := &mockStreamHandler{}
+	info := &grpc.StreamServerInfo{FullMethod: "/search.Search/Query", IsServerStream: true}
+	_ = StreamServerInterceptor(WithTransactionNamer(ServiceMethodName, sentry.SourceRoute))(nil, &mockServerStream{ctx: ctx}, info, handler.handle)
+
+	transactions := transport.Transactions()
+	if len(transactions) != 1 || transactions[0].Transaction != "Search/Query" {
+		t.Fatalf("Expected 1 transaction named Search/Query, got %v", transactions)
+	}
+	if transactions[0].Contexts["trace"]["description"] != "/search.Search/Query" {
+		t.Errorf("Expected the full method as description, got %v", transactions[0].Contexts["trace"]["description"])
+	}
+}
@@ -2,8 +2,12 @@
 package grpc_sentry
 
 import (
+	"context"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/getsentry/sentry-go"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -14,12 +18,35 @@ const (
 )
 
 var defaultOptions = &options{
-	Repanic:               false,
-	WaitForDelivery:       false,
-	ReportOn:              ReportAlways,
-	Timeout:               1 * time.Second,
-	OperationNameOverride: "",
-	CaptureRequestBody:    true,
+	Repanic:                   false,
+	WaitForDelivery:           false,
+	ReportOn:                  ReportAlways,
+	Timeout:                   1 * time.Second,
+	OperationNameOverride:     "",
+	TransactionNamer:          FullMethodName,
+	TransactionSource:         sentry.SourceRoute,
+	CaptureRequestBody:        true,
+	MaxCapturedStreamMessages: 1,
+	CaptureResponseBody:       false,
+	BodyProtoNames:            false,
+	BodyEmitDefaults:          false,
+	BodyMaxDepth:              10,
+	MaxPayloadSize:            10 * 1024,
+	MaxPayloadFieldSize:       1024,
+	MaxRepeatedFieldItems:     50,
+	StreamErrorBreadcrumbs:    false,
+	CaptureStreamErrors:       false,
+	StreamMessageSpans:        false,
+	MaxStreamMessageSpans:     100,
+	StreamMessageSampleRate:   1.0,
+	IgnoreDefaultMethods:      true,
+	CaptureMetadata:           false,
+	DenyDefaultMetadata:       true,
+	TagPrefix:                 "",
+	MaxTagValueLength:         200,
+	Levels:                    defaultLevels,
+	Fingerprinter:             DefaultFingerprint,
+	RecoveryHandler:           RecoverWithCode(codes.Internal),
 }
 
 type options struct {
@@ -34,12 +61,163 @@ type options struct {
 
 	ReportOn func(error) bool
 
+	// ReportPredicate, when set, takes precedence over ReportOn to decide which errors are reported.
+	ReportPredicate ReportPredicate
+
 	OperationNameOverride string
 
+	// TransactionNamer names the transactions of the server interceptors.
+	TransactionNamer TransactionNamer
+
+	// TransactionSource is the source of the names given by TransactionNamer.
+	TransactionSource sentry.TransactionSource
+
 	// CaptureRequestBody configures whether the request body should be sent to Sentry.
 	CaptureRequestBody bool
+
+	// MaxCapturedStreamMessages is the number of messages sent on a client stream that are kept to be sent to Sentry.
+	MaxCapturedStreamMessages int
+
+	// CaptureResponseBody configures whether the response of unary calls is sent to Sentry along with reported errors.
+	CaptureResponseBody bool
+
+	// BodyProtoNames configures whether captured protobuf messages use the field names from the .proto file instead
+	// of their lowerCamelCase JSON names.
+	BodyProtoNames bool
+
+	// BodyEmitDefaults configures whether captured protobuf messages include fields set to their default value.
+	BodyEmitDefaults bool
+
+	// BodyMaxDepth limits how deep objects and arrays of captured messages are rendered, zero meaning no limit.
+	BodyMaxDepth int
+
+	// MaxPayloadSize is the maximum size in bytes of a captured payload. Larger payloads are replaced by their type
+	// and size. Zero means no limit.
+	MaxPayloadSize int
+
+	// MaxPayloadFieldSize is the maximum size in bytes of string and bytes fields of captured messages.
+	MaxPayloadFieldSize int
+
+	// MaxRepeatedFieldItems is the maximum number of items rendered for repeated fields of captured messages.
+	MaxRepeatedFieldItems int
+
+	// RedactFieldPaths lists dot-separated paths of fields, e.g. "credentials.token", redacted from captured messages.
+	RedactFieldPaths []string
+
+	// RedactFieldNames lists case-insensitive glob patterns of field names redacted wherever they appear.
+	RedactFieldNames []string
+
+	// PayloadScrubber is called on every captured payload after the built-in redaction.
+	PayloadScrubber PayloadScrubber
+
+	// StreamErrorBreadcrumbs configures whether failed SendMsg/RecvMsg calls on server streams leave a breadcrumb.
+	StreamErrorBreadcrumbs bool
+
+	// CaptureStreamErrors configures whether failed SendMsg/RecvMsg calls on server streams are captured on their own.
+	CaptureStreamErrors bool
+
+	// StreamMessageSpans configures whether every SendMsg/RecvMsg on a stream is traced as a child span, and the
+	// bytes flowing through the stream are counted.
+	StreamMessageSpans bool
+
+	// MaxStreamMessageSpans caps the number of message spans recorded for a single stream.
+	MaxStreamMessageSpans int
+
+	// StreamMessageSampleRate is the probability, between 0 and 1, that a stream message is traced.
+	StreamMessageSampleRate float64
+
+	// IgnoreMethods lists the methods that are not instrumented, see methodPattern for the accepted patterns.
+	IgnoreMethods []string
+
+	// OnlyMethods, when not empty, restricts instrumentation to the matching methods.
+	OnlyMethods []string
+
+	// IgnoreDefaultMethods configures whether health checks and reflection are ignored.
+	IgnoreDefaultMethods bool
+
+	// CaptureMetadata configures whether the allowed request metadata is attached to events and spans, and the
+	// response header and trailer of failed client calls to events.
+	CaptureMetadata bool
+
+	// AllowedMetadata lists case-insensitive glob patterns of the metadata keys captured. When empty, every key that
+	// is not denied is captured.
+	AllowedMetadata []string
+
+	// DeniedMetadata lists case-insensitive glob patterns of the metadata keys never captured.
+	DeniedMetadata []string
+
+	// DenyDefaultMetadata configures whether the metadata keys usually carrying credentials are denied.
+	DenyDefaultMetadata bool
+
+	// MetadataTags lists the metadata keys promoted to tags of events and spans, unless they are denied.
+	MetadataTags []string
+
+	// TagPrefix is prepended to the keys of the grpc_tags values set as tags of reported events.
+	TagPrefix string
+
+	// MaxTagValueLength is the maximum number of characters of tag values set from grpc_tags, zero meaning no limit.
+	MaxTagValueLength int
+
+	// Levels maps status codes to the level of the events reporting them. It must not be modified in place, as it
+	// is shared with the default options.
+	Levels map[codes.Code]sentry.Level
+
+	// LevelFunc, when set, takes precedence over Levels to decide the level of reported events.
+	LevelFunc LevelFunc
+
+	// Fingerprinter groups reported errors into issues, nil leaving the grouping to Sentry.
+	Fingerprinter Fingerprinter
+
+	// Hub, when set, is the hub every RPC reports through instead of the one found on the context.
+	Hub *sentry.Hub
+
+	// Client, when set, is bound to the hub of every RPC so that events are sent with it.
+	Client *sentry.Client
+
+	// HubFactory, when set, returns the hub of every RPC and takes precedence over Hub.
+	HubFactory HubFactory
+
+	// ClientRouter, when set, picks the client of every RPC handled by the server interceptors.
+	ClientRouter ClientRouter
+
+	// BeforeCapture, when set, is called before every error is captured and may drop the event.
+	BeforeCapture BeforeCapture
+
+	// EventProcessors only run for the events captured while an RPC is in progress.
+	EventProcessors []sentry.EventProcessor
+
+	// UserExtractor, when set, identifies the user of the events captured by the server interceptors.
+	UserExtractor UserExtractor
+
+	// RecoveryHandler converts a recovered panic into the error returned to the caller.
+	RecoveryHandler RecoveryHandlerFunc
+
+	methods         *methodFilter
+	methodOverrides []methodOverride
+	resolved        *sync.Map // full method name -> *options
+	resolvedCount   *atomic.Int64
+}
+
+// RecoveryHandlerFunc converts a value recovered from a panic into the error returned to the caller. The eventID
+// identifies the event reported to Sentry and is nil when no event was sent.
+type RecoveryHandlerFunc func(ctx context.Context, p interface{}, eventID *sentry.EventID) error
+
+// ReportInfo describes a finished call for the benefit of a ReportPredicate.
+type ReportInfo struct {
+	// FullMethod is the full gRPC method name, e.g. "/package.Service/Method".
+	FullMethod string
+
+	// Request is the request message of unary calls and nil for streams.
+	Request interface{}
+
+	// Duration is the time elapsed since the call started.
+	Duration time.Duration
 }
 
+// ReportPredicate decides whether an error returned by a call should be reported to Sentry. Unlike the reporter
+// functions used with WithReportOn it can take the context and details of the call into account.
+type ReportPredicate func(ctx context.Context, info ReportInfo, err error) bool
+
 // ReportAlways is a reporter function that always reports errors to Sentry.
 func ReportAlways(error) bool {
 	return true
@@ -56,3 +234,68 @@ func ReportOnCodes(cc ...codes.Code) func(error) bool {
 		return false
 	}
 }
+
+// RecoverWithCode returns a recovery handler that converts panics into a gRPC status error with the specified code.
+// The Sentry event ID is included in the status message so that callers can reference the reported event.
+func RecoverWithCode(c codes.Code) RecoveryHandlerFunc {
+	return func(_ context.Context, _ interface{}, eventID *sentry.EventID) error {
+		if eventID == nil {
+			return status.Error(c, "panic recovered")
+		}
+		return status.Errorf(c, "panic recovered (sentry event id: %s)", *eventID)
+	}
+}
+
+// ReportOnCodesExcept returns a reporter function that reports all errors except the ones matching the specified
+// gRPC status codes.
+func ReportOnCodesExcept(cc ...codes.Code) func(error) bool {
+	report := ReportOnCodes(cc...)
+	return func(err error) bool {
+		return !report(err)
+	}
+}
+
+// ReportError adapts a reporter function that only looks at the error, such as ReportOnCodes, into a ReportPredicate.
+func ReportError(f func(error) bool) ReportPredicate {
+	return func(_ context.Context, _ ReportInfo, err error) bool {
+		return f(err)
+	}
+}
+
+// ReportOnSlowerThan returns a predicate that only reports errors of calls that took longer than d.
+func ReportOnSlowerThan(d time.Duration) ReportPredicate {
+	return func(_ context.Context, info ReportInfo, _ error) bool {
+		return info.Duration > d
+	}
+}
+
+// ReportAnd returns a predicate that reports an error when all of the predicates do.
+func ReportAnd(ps ...ReportPredicate) ReportPredicate {
+	return func(ctx context.Context, info ReportInfo, err error) bool {
+		for _, p := range ps {
+			if !p(ctx, info, err) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ReportOr returns a predicate that reports an error when any of the predicates does.
+func ReportOr(ps ...ReportPredicate) ReportPredicate {
+	return func(ctx context.Context, info ReportInfo, err error) bool {
+		for _, p := range ps {
+			if p(ctx, info, err) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ReportNot returns a predicate that reports an error when p does not.
+func ReportNot(p ReportPredicate) ReportPredicate {
+	return func(ctx context.Context, info ReportInfo, err error) bool {
+		return !p(ctx, info, err)
+	}
+}
@@ -2,9 +2,12 @@
 package grpc_sentry
 
 import (
+	"context"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/getsentry/sentry-go"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -223,3 +226,100 @@ func TestDefaultOperationNames(t *testing.T) {
 		t.Errorf("Expected defaultClientOperationName to be 'grpc.client', got %s", defaultClientOperationName)
 	}
 }
+
+func TestRecoverWithCode(t *testing.T) {
+	eventID := sentry.EventID("0123456789abcdef0123456789abcdef")
+
+	tests := []struct {
+		name    string
+		code    codes.Code
+		eventID *sentry.EventID
+	}{
+		{
+			name:    "with event id",
+			code:    codes.Internal,
+			eventID: &eventID,
+		},
+		{
+			name:    "without event id",
+			code:    codes.Unknown,
+			eventID: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := RecoverWithCode(tt.code)(context.Background(), "panic", tt.eventID)
+
+			if status.Code(err) != tt.code {
+				t.Errorf("Expected code %s, got %s", tt.code, status.Code(err))
+			}
+			if tt.eventID != nil && !strings.Contains(err.Error(), string(*tt.eventID)) {
+				t.Errorf("Expected error %q to contain event id %s", err.Error(), *tt.eventID)
+			}
+		})
+	}
+}
+
+func TestReportOnCodesExcept(t *testing.T) {
+	reporter := ReportOnCodesExcept(codes.NotFound, codes.InvalidArgument)
+
+	tests := []struct {
+		name         string
+		error        error
+		shouldReport bool
+	}{
+		{"excluded code", status.Error(codes.NotFound, "not found"), false},
+		{"other excluded code", status.Error(codes.InvalidArgument, "invalid"), false},
+		{"included code", status.Error(codes.Internal, "internal"), true},
+		{"non-status error", &testError{message: "test error"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reporter(tt.error); got != tt.shouldReport {
+				t.Errorf("Expected ReportOnCodesExcept to return %v for %v, got %v", tt.shouldReport, tt.error, got)
+			}
+		})
+	}
+}
+
+func TestReportPredicates(t *testing.T) {
+	always := ReportError(ReportAlways)
+	never := ReportNot(always)
+	slow := ReportOnSlowerThan(5 * time.Second)
+	deadline := ReportError(ReportOnCodes(codes.DeadlineExceeded))
+	// Report deadline errors only when the handler actually ran over 5s, and all other errors.
+	combined := ReportOr(ReportError(ReportOnCodesExcept(codes.DeadlineExceeded)), ReportAnd(deadline, slow))
+
+	fast := ReportInfo{FullMethod: "/test.Service/Method", Duration: time.Second}
+	slowCall := ReportInfo{FullMethod: "/test.Service/Method", Duration: 6 * time.Second}
+	deadlineErr := status.Error(codes.DeadlineExceeded, "deadline exceeded")
+	internalErr := status.Error(codes.Internal, "internal")
+
+	tests := []struct {
+		name         string
+		predicate    ReportPredicate
+		info         ReportInfo
+		error        error
+		shouldReport bool
+	}{
+		{"always", always, fast, internalErr, true},
+		{"not always", never, fast, internalErr, false},
+		{"slow call", slow, slowCall, internalErr, true},
+		{"fast call", slow, fast, internalErr, false},
+		{"and of nothing", ReportAnd(), fast, internalErr, true},
+		{"or of nothing", ReportOr(), fast, internalErr, false},
+		{"fast deadline", combined, fast, deadlineErr, false},
+		{"slow deadline", combined, slowCall, deadlineErr, true},
+		{"fast internal", combined, fast, internalErr, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.predicate(context.Background(), tt.info, tt.error); got != tt.shouldReport {
+				t.Errorf("Expected predicate to return %v, got %v", tt.shouldReport, got)
+			}
+		})
+	}
+}
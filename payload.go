@@ -0,0 +1,336 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// maxDepthMarker replaces objects and arrays nested deeper than the configured maximum depth.
+const maxDepthMarker = "[max depth exceeded]"
+
+// oversizedPayloadFactor is how many times over MaxPayloadSize the wire size of a message may be for it to be rendered
+// and truncated rather than summarized right away.
+const oversizedPayloadFactor = 16
+
+// Markers appended to truncated values, formatted with the amount of data left out.
+const (
+	truncatedBytesMarker = "...[%d bytes truncated]"
+	truncatedItemsMarker = "[%d more items truncated]"
+	oversizedMarker      = "[payload exceeds the limit of %d bytes]"
+)
+
+// fieldVisitor is called by walkMessage for every field of a decoded message, with fieldPath the dot-separated
+// .proto names leading to the field from the root message, e.g. "credentials.token". Map keys and list indexes are
+// not part of the path. The field's value is parent[key]. The visitor returns whether walkMessage should descend
+// into the field.
+type fieldVisitor func(fd protoreflect.FieldDescriptor, fieldPath string, parent map[string]interface{}, key string) bool
+
+// encodePayload renders a request or response message of fullMethod for Sentry. Protobuf messages are rendered as
+// JSON following the protobuf JSON mapping, which leaves out internal fields of the generated structs, and have their
+// sensitive fields redacted; any other value is rendered with fmt. The configured PayloadScrubber runs last.
+func encodePayload(fullMethod string, m interface{}, o *options) string {
+	msg, ok := m.(proto.Message)
+	if !ok {
+		return truncateString(scrubString(fullMethod, fmt.Sprintf("%+v", m), o), o.MaxPayloadSize)
+	}
+
+	// The JSON rendering of a message is larger than its wire format, so without field truncation to bring it
+	// back under the limit there is no point in rendering a message that is already too large. Even with field
+	// truncation, a message far beyond the limit is not worth rendering, decoding and truncating, which takes several
+	// times its size in memory only to keep a small part of it.
+	if o.MaxPayloadSize > 0 {
+		size := proto.Size(msg)
+		if size > oversizedPayloadFactor*o.MaxPayloadSize ||
+			size > o.MaxPayloadSize && o.MaxPayloadFieldSize <= 0 && o.MaxRepeatedFieldItems <= 0 {
+			return payloadSummary(msg, o)
+		}
+	}
+
+	marshal := protojson.MarshalOptions{
+		UseProtoNames:   o.BodyProtoNames,
+		EmitUnpopulated: o.BodyEmitDefaults,
+	}
+	b, err := marshal.Marshal(msg)
+	if err != nil {
+		// Invalid UTF-8 or an unresolvable Any cannot be rendered as JSON, and thus not redacted either.
+		return redactedMarker
+	}
+
+	// Decode the output to work on its structure. This also normalizes the whitespace that protojson randomizes.
+	var tree interface{}
+	decoder := json.NewDecoder(bytes.NewReader(b))
+	decoder.UseNumber()
+	if err := decoder.Decode(&tree); err != nil {
+		// Never send a payload that could not be redacted.
+		return redactedMarker
+	}
+
+	if fields, ok := tree.(map[string]interface{}); ok {
+		newRedactor(o).redactMessage(fields, msg.ProtoReflect().Descriptor())
+		truncateFields(fields, msg.ProtoReflect().Descriptor(), o)
+	}
+	tree = limitDepth(tree, 1, o.BodyMaxDepth)
+	if o.PayloadScrubber != nil {
+		tree = o.PayloadScrubber(fullMethod, tree)
+	}
+
+	out, err := json.Marshal(tree)
+	if err != nil {
+		return redactedMarker
+	}
+	if o.MaxPayloadSize > 0 && len(out) > o.MaxPayloadSize {
+		return payloadSummary(msg, o)
+	}
+	return string(out)
+}
+
+// payloadSummary describes a message that is too large to be sent by its type and size only.
+func payloadSummary(msg proto.Message, o *options) string {
+	out, _ := json.Marshal(map[string]interface{}{
+		"type":      msg.ProtoReflect().Descriptor().FullName(),
+		"size":      proto.Size(msg),
+		"truncated": fmt.Sprintf(oversizedMarker, o.MaxPayloadSize),
+	})
+	return string(out)
+}
+
+// truncateFields shortens the string and bytes fields of the decoded message v described by md to
+// MaxPayloadFieldSize bytes, and its repeated fields to MaxRepeatedFieldItems items, leaving a marker of the amount
+// of data left out.
+func truncateFields(v map[string]interface{}, md protoreflect.MessageDescriptor, o *options) {
+	walkMessage(v, md, "", func(fd protoreflect.FieldDescriptor, _ string, parent map[string]interface{}, key string) bool {
+		switch {
+		case fd.IsMap():
+			entries, _ := parent[key].(map[string]interface{})
+			for k, entry := range entries {
+				entries[k] = truncateScalar(fd.MapValue(), entry, o.MaxPayloadFieldSize)
+			}
+		case fd.IsList():
+			items, _ := parent[key].([]interface{})
+			var more int
+			if o.MaxRepeatedFieldItems > 0 && len(items) > o.MaxRepeatedFieldItems {
+				more = len(items) - o.MaxRepeatedFieldItems
+				items = items[:o.MaxRepeatedFieldItems]
+			}
+			for i, item := range items {
+				items[i] = truncateScalar(fd, item, o.MaxPayloadFieldSize)
+			}
+			if more > 0 {
+				parent[key] = append(items, fmt.Sprintf(truncatedItemsMarker, more))
+			}
+		default:
+			parent[key] = truncateScalar(fd, parent[key], o.MaxPayloadFieldSize)
+		}
+		return true
+	})
+}
+
+// truncateScalar shortens a decoded string or bytes value of fd to maxSize bytes. Other values are returned as is.
+func truncateScalar(fd protoreflect.FieldDescriptor, v interface{}, maxSize int) interface{} {
+	s, ok := v.(string)
+	if !ok || maxSize <= 0 {
+		return v
+	}
+
+	switch fd.Kind() {
+	case protoreflect.StringKind:
+		return truncateString(s, maxSize)
+	case protoreflect.BytesKind:
+		b, err := base64.StdEncoding.DecodeString(s)
+		if err != nil || len(b) <= maxSize {
+			return v
+		}
+		return base64.StdEncoding.EncodeToString(b[:maxSize]) + fmt.Sprintf(truncatedBytesMarker, len(b)-maxSize)
+	default:
+		return v
+	}
+}
+
+// truncateString shortens s to at most maxSize bytes without splitting a UTF-8 sequence, followed by a marker.
+func truncateString(s string, maxSize int) string {
+	if maxSize <= 0 || len(s) <= maxSize {
+		return s
+	}
+	n := maxSize
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + fmt.Sprintf(truncatedBytesMarker, len(s)-n)
+}
+
+// walkMessage calls visit for the fields of the decoded message v described by md, descending into nested messages,
+// lists and maps of messages.
+func walkMessage(v map[string]interface{}, md protoreflect.MessageDescriptor, prefix string, visit fieldVisitor) {
+	if md.FullName() == "google.protobuf.Any" {
+		// The fields of an Any are the ones of the message it holds, inlined next to its type URL.
+		typeURL, _ := v["@type"].(string)
+		mt, err := protoregistry.GlobalTypes.FindMessageByURL(typeURL)
+		if err != nil {
+			return
+		}
+		md = mt.Descriptor()
+	}
+
+	fields := md.Fields()
+	for key := range v {
+		fd := fields.ByJSONName(key)
+		if fd == nil {
+			fd = fields.ByName(protoreflect.Name(key))
+		}
+		if fd == nil {
+			continue
+		}
+
+		fieldPath := string(fd.Name())
+		if prefix != "" {
+			fieldPath = prefix + "." + fieldPath
+		}
+		if !visit(fd, fieldPath, v, key) {
+			continue
+		}
+
+		switch child := v[key]; {
+		case fd.IsMap():
+			if fd.MapValue().Message() == nil {
+				continue
+			}
+			entries, _ := child.(map[string]interface{})
+			for _, entry := range entries {
+				if m, ok := entry.(map[string]interface{}); ok {
+					walkMessage(m, fd.MapValue().Message(), fieldPath, visit)
+				}
+			}
+		case fd.Message() != nil && fd.IsList():
+			items, _ := child.([]interface{})
+			for _, item := range items {
+				if m, ok := item.(map[string]interface{}); ok {
+					walkMessage(m, fd.Message(), fieldPath, visit)
+				}
+			}
+		case fd.Message() != nil:
+			// Well-known types such as Timestamp are not rendered as objects and are left alone.
+			if m, ok := child.(map[string]interface{}); ok {
+				walkMessage(m, fd.Message(), fieldPath, visit)
+			}
+		}
+	}
+}
+
+// scrubString passes the string rendering of a payload through the configured PayloadScrubber.
+func scrubString(fullMethod, s string, o *options) string {
+	if o.PayloadScrubber == nil {
+		return s
+	}
+	switch v := o.PayloadScrubber(fullMethod, s).(type) {
+	case string:
+		return v
+	default:
+		out, err := json.Marshal(v)
+		if err != nil {
+			return redactedMarker
+		}
+		return string(out)
+	}
+}
+
+// limitDepth replaces the objects and arrays of a decoded JSON value that are nested deeper than maxDepth. A maxDepth
+// of zero or less disables the limit.
+func limitDepth(v interface{}, depth, maxDepth int) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		if maxDepth > 0 && depth > maxDepth {
+			return maxDepthMarker
+		}
+		for k, child := range value {
+			value[k] = limitDepth(child, depth+1, maxDepth)
+		}
+	case []interface{}:
+		if maxDepth > 0 && depth > maxDepth {
+			return maxDepthMarker
+		}
+		for i, child := range value {
+			value[i] = limitDepth(child, depth+1, maxDepth)
+		}
+	}
+	return v
+}
+
+// attachRequestBody attaches the request to the events captured during the call as their request data. The request
+// is only rendered when an event is actually captured.
+func attachRequestBody(events *callEvents, fullMethod string, req interface{}, o *options) {
+	events.addEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		if event.Type == "transaction" {
+			return event
+		}
+		if event.Request == nil {
+			event.Request = &sentry.Request{}
+		}
+		event.Request.Data = encodePayload(fullMethod, req, o)
+		return event
+	})
+}
+
+// attachStreamRequestBody attaches the messages sent so far on a stream to the events captured during the call as
+// their request data, rendered as a JSON array.
+func attachStreamRequestBody(events *callEvents, fullMethod string, sent *messageLog, o *options) {
+	events.addEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		messages := sent.snapshot()
+		if event.Type == "transaction" || len(messages) == 0 {
+			return event
+		}
+
+		rendered := make([]json.RawMessage, 0, len(messages))
+		for _, m := range messages {
+			payload := encodePayload(fullMethod, m, o)
+			if !json.Valid([]byte(payload)) {
+				quoted, _ := json.Marshal(payload)
+				payload = string(quoted)
+			}
+			rendered = append(rendered, json.RawMessage(payload))
+		}
+		data, err := json.Marshal(rendered)
+		if err != nil {
+			return event
+		}
+
+		if event.Request == nil {
+			event.Request = &sentry.Request{}
+		}
+		event.Request.Data = string(data)
+		return event
+	})
+}
+
+// hasResponse reports whether resp holds a response worth attaching, leaving out nil and typed nil values as well as
+// invalid proto messages, e.g. the nil pointer a handler returns along with its error.
+func hasResponse(resp interface{}) bool {
+	if isNil(resp) {
+		return false
+	}
+	if m, ok := resp.(proto.Message); ok {
+		return m.ProtoReflect().IsValid()
+	}
+	return true
+}
+
+// attachResponseBody attaches the response to the events captured during the call as their response context. It is
+// meant to be called right before an error is captured, so that responses only end up on reported events.
+func attachResponseBody(events *callEvents, fullMethod string, resp interface{}, o *options) {
+	events.addEventProcessor(func(event *sentry.Event, _ *sentry.EventHint) *sentry.Event {
+		if event.Type == "transaction" {
+			return event
+		}
+		responseContext(event)["data"] = encodePayload(fullMethod, resp, o)
+		return event
+	})
+}
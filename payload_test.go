@@ -0,0 +1,318 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// testAccountDescriptor describes the messages used to test payload rendering:
+//
+//	message Address {
+//	  string street = 1;
+//	  string city = 2;
+//	  Address next = 3;
+//	}
+//
+//	message Account {
+//	  string user_name = 1;
+//	  string password = 2 [debug_redact = true];
+//	  bytes avatar = 3;
+//	  repeated string tags = 4;
+//	  Address address = 5;
+//	  map<string, string> labels = 6;
+//	  int64 id = 7;
+//	}
+func testAccountDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, label descriptorpb.FieldDescriptorProto_Label) *descriptorpb.FieldDescriptorProto {
+		return &descriptorpb.FieldDescriptorProto{
+			Name:   proto.String(name),
+			Number: proto.Int32(number),
+			Type:   typ.Enum(),
+			Label:  label.Enum(),
+		}
+	}
+	optional := descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL
+	repeated := descriptorpb.FieldDescriptorProto_LABEL_REPEATED
+
+	next := field("next", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional)
+	next.TypeName = proto.String(".grpc_sentry.test.Address")
+	address := field("address", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, optional)
+	address.TypeName = proto.String(".grpc_sentry.test.Address")
+	labels := field("labels", 6, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, repeated)
+	labels.TypeName = proto.String(".grpc_sentry.test.Account.LabelsEntry")
+	password := field("password", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional)
+	password.Options = &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+
+	file := &descriptorpb.FileDescriptorProto{
+		Name:    proto.String("grpc_sentry_test.proto"),
+		Package: proto.String("grpc_sentry.test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Address"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("street", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+					field("city", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+					next,
+				},
+			},
+			{
+				Name: proto.String("Account"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("user_name", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+					password,
+					field("avatar", 3, descriptorpb.FieldDescriptorProto_TYPE_BYTES, optional),
+					field("tags", 4, descriptorpb.FieldDescriptorProto_TYPE_STRING, repeated),
+					address,
+					labels,
+					field("id", 7, descriptorpb.FieldDescriptorProto_TYPE_INT64, optional),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("LabelsEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+							field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, optional),
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			},
+		},
+	}
+
+	fd, err := protodesc.NewFile(file, nil)
+	if err != nil {
+		t.Fatalf("Failed to build test descriptor: %v", err)
+	}
+	return fd.Messages().ByName("Account")
+}
+
+// newTestAccount returns an Account message populated from its JSON representation
+func newTestAccount(t *testing.T, js string) proto.Message {
+	t.Helper()
+
+	msg := dynamicpb.NewMessage(testAccountDescriptor(t))
+	if err := protojson.Unmarshal([]byte(js), msg); err != nil {
+		t.Fatalf("Failed to populate test message: %v", err)
+	}
+	return msg
+}
+
+// decodePayload decodes a rendered payload for inspection
+func decodePayload(t *testing.T, payload string) map[string]interface{} {
+	t.Helper()
+
+	var m map[string]interface{}
+	if err := json.Unmarshal([]byte(payload), &m); err != nil {
+		t.Fatalf("Expected payload to be JSON, got %q: %v", payload, err)
+	}
+	return m
+}
+
+func TestEncodePayload_NonProto(t *testing.T) {
+	type request struct {
+		Name string
+	}
+
+	got := encodePayload("/test.Service/Method", request{Name: "jane"}, newConfig([]Option{}))
+	if got != "{Name:jane}" {
+		t.Errorf("Expected non-proto values to be rendered with fmt, got %q", got)
+	}
+}
+
+func TestEncodePayload_FieldNames(t *testing.T) {
+	msg := newTestAccount(t, `{"userName": "jane"}`)
+
+	tests := []struct {
+		name    string
+		options []Option
+		key     string
+	}{
+		{"json names by default", []Option{}, "userName"},
+		{"proto names", []Option{WithBodyProtoNames(true)}, "user_name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := decodePayload(t, encodePayload("/test.Service/Method", msg, newConfig(tt.options)))
+			if m[tt.key] != "jane" {
+				t.Errorf("Expected field %q to be rendered, got %v", tt.key, m)
+			}
+		})
+	}
+}
+
+func TestEncodePayload_EmitDefaults(t *testing.T) {
+	msg := newTestAccount(t, `{"userName": "jane"}`)
+
+	m := decodePayload(t, encodePayload("/test.Service/Method", msg, newConfig([]Option{})))
+	if _, ok := m["id"]; ok {
+		t.Errorf("Expected default values to be left out, got %v", m)
+	}
+
+	m = decodePayload(t, encodePayload("/test.Service/Method", msg, newConfig([]Option{WithBodyEmitDefaults(true)})))
+	if _, ok := m["id"]; !ok {
+		t.Errorf("Expected default values to be emitted, got %v", m)
+	}
+}
+
+func TestEncodePayload_MarshalError(t *testing.T) {
+	msg := newTestAccount(t, `{"password": "hunter2"}`)
+	// protojson refuses to render invalid UTF-8, which cannot be set through JSON either.
+	userName := msg.ProtoReflect().Descriptor().Fields().ByName("user_name")
+	msg.ProtoReflect().Set(userName, protoreflect.ValueOfString("bad\xffutf8"))
+
+	got := encodePayload("/test.Service/Method", msg, newConfig([]Option{}))
+	if got != redactedMarker {
+		t.Errorf("Expected a payload that cannot be rendered to be replaced by %q, got %q", redactedMarker, got)
+	}
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("Expected redacted field to be left out, got %q", got)
+	}
+}
+
+func TestEncodePayload_MaxDepth(t *testing.T) {
+	msg := newTestAccount(t, `{"address": {"city": "Paris", "next": {"city": "Lyon", "next": {"city": "Nice"}}}}`)
+
+	m := decodePayload(t, encodePayload("/test.Service/Method", msg, newConfig([]Option{WithBodyMaxDepth(2)})))
+	address, ok := m["address"].(map[string]interface{})
+	if !ok || address["city"] != "Paris" {
+		t.Fatalf("Expected address to be rendered, got %v", m)
+	}
+	if address["next"] != maxDepthMarker {
+		t.Errorf("Expected nested address to be replaced by %q, got %v", maxDepthMarker, address["next"])
+	}
+
+	payload := encodePayload("/test.Service/Method", msg, newConfig([]Option{WithBodyMaxDepth(0)}))
+	if !strings.Contains(payload, "Nice") {
+		t.Errorf("Expected no depth limit, got %s", payload)
+	}
+}
+
+func TestUnaryServerInterceptor_RequestBody(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryServerInterceptor()
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "internal")}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+	_, _ = interceptor(ctx, newTestAccount(t, `{"userName": "jane"}`), info, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Request == nil {
+		t.Fatalf("Expected 1 event with request data, got %v", events)
+	}
+	if m := decodePayload(t, events[0].Request.Data); m["userName"] != "jane" {
+		t.Errorf("Expected request to be rendered as JSON, got %s", events[0].Request.Data)
+	}
+	if n := len(transport.Transactions()); n != 1 || transport.Transactions()[0].Request != nil {
+		t.Error("Expected the request body not to be attached to the transaction")
+	}
+}
+
+func TestEncodePayload_FieldTruncation(t *testing.T) {
+	avatar := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 100))
+	msg := newTestAccount(t, `{
+		"userName": "`+strings.Repeat("é", 20)+`",
+		"avatar": "`+avatar+`",
+		"tags": ["a", "b", "c", "d", "e"],
+		"labels": {"team": "`+strings.Repeat("x", 30)+`"}
+	}`)
+	o := newConfig([]Option{WithMaxPayloadFieldSize(15), WithMaxRepeatedFieldItems(2)})
+
+	m := decodePayload(t, encodePayload("/test.Service/Method", msg, o))
+
+	if want := strings.Repeat("é", 7) + "...[26 bytes truncated]"; m["userName"] != want {
+		t.Errorf("Expected string to be truncated on a rune boundary to %q, got %q", want, m["userName"])
+	}
+	if want := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 15)) + "...[85 bytes truncated]"; m["avatar"] != want {
+		t.Errorf("Expected bytes to be truncated to %q, got %q", want, m["avatar"])
+	}
+	tags, _ := m["tags"].([]interface{})
+	if len(tags) != 3 || tags[2] != "[3 more items truncated]" {
+		t.Errorf("Expected repeated field to be truncated to 2 items and a marker, got %v", m["tags"])
+	}
+	labels, _ := m["labels"].(map[string]interface{})
+	if want := strings.Repeat("x", 15) + "...[15 bytes truncated]"; labels["team"] != want {
+		t.Errorf("Expected map value to be truncated to %q, got %v", want, labels["team"])
+	}
+}
+
+func TestEncodePayload_MaxPayloadSize(t *testing.T) {
+	avatar := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{0xff}, 4096))
+	msg := newTestAccount(t, `{"userName": "jane", "avatar": "`+avatar+`"}`)
+
+	tests := []struct {
+		name    string
+		options []Option
+		summary bool
+	}{
+		{
+			name:    "field truncation brings payload under the limit",
+			options: []Option{WithMaxPayloadSize(1024), WithMaxPayloadFieldSize(64)},
+			summary: false,
+		},
+		{
+			name:    "payload over the limit",
+			options: []Option{WithMaxPayloadSize(1024), WithMaxPayloadFieldSize(0)},
+			summary: true,
+		},
+		{
+			name:    "payload far over the limit despite field truncation",
+			options: []Option{WithMaxPayloadSize(128), WithMaxPayloadFieldSize(16)},
+			summary: true,
+		},
+		{
+			name:    "payload over the limit without field truncation",
+			options: []Option{WithMaxPayloadSize(1024), WithMaxPayloadFieldSize(0), WithMaxRepeatedFieldItems(0)},
+			summary: true,
+		},
+		{
+			name:    "no limit",
+			options: []Option{WithMaxPayloadSize(0), WithMaxPayloadFieldSize(0)},
+			summary: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := decodePayload(t, encodePayload("/test.Service/Method", msg, newConfig(tt.options)))
+
+			if tt.summary {
+				if m["type"] != "grpc_sentry.test.Account" || m["size"] != float64(proto.Size(msg)) {
+					t.Errorf("Expected payload to be replaced by its type and size, got %v", m)
+				}
+				if m["truncated"] != fmt.Sprintf(oversizedMarker, newConfig(tt.options).MaxPayloadSize) {
+					t.Errorf("Expected payload to carry a truncation marker, got %v", m["truncated"])
+				}
+			} else if m["userName"] != "jane" {
+				t.Errorf("Expected payload to be rendered, got %v", m)
+			}
+		})
+	}
+
+	long := strings.Repeat("x", 2048)
+	if got := encodePayload("/test.Service/Method", long, newConfig([]Option{WithMaxPayloadSize(1024)})); len(got) > 1024+len("...[1024 bytes truncated]") || !strings.HasSuffix(got, "...[1024 bytes truncated]") {
+		t.Errorf("Expected non-proto payload to be truncated, got %d bytes", len(got))
+	}
+}
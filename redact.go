@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"path"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// redactedMarker replaces the value of redacted fields, matching the marker used by Sentry's own scrubbing.
+const redactedMarker = "[Filtered]"
+
+// PayloadScrubber is called with the decoded JSON representation of every captured payload of fullMethod, after
+// the built-in redaction has been applied, and returns the value to send instead. Payloads that are not protobuf
+// messages are passed as their string rendering.
+type PayloadScrubber func(fullMethod string, payload interface{}) interface{}
+
+// redactor removes sensitive fields from the decoded JSON representation of protobuf messages. A field is redacted
+// when it is marked with the debug_redact option, when its path matches one of paths, or when its name matches one
+// of names.
+type redactor struct {
+	paths []string
+	names []string
+}
+
+func newRedactor(o *options) *redactor {
+	names := make([]string, 0, len(o.RedactFieldNames))
+	for _, n := range o.RedactFieldNames {
+		names = append(names, strings.ToLower(n))
+	}
+	return &redactor{paths: o.RedactFieldPaths, names: names}
+}
+
+// redacts reports whether the field found at fieldPath must be redacted.
+func (r *redactor) redacts(fd protoreflect.FieldDescriptor, fieldPath string) bool {
+	if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts.GetDebugRedact() {
+		return true
+	}
+	for _, p := range r.paths {
+		if ok, _ := path.Match(p, fieldPath); ok {
+			return true
+		}
+	}
+	name := strings.ToLower(string(fd.Name()))
+	for _, n := range r.names {
+		if ok, _ := path.Match(n, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// redactMessage redacts the fields of the decoded message v described by md.
+func (r *redactor) redactMessage(v map[string]interface{}, md protoreflect.MessageDescriptor) {
+	walkMessage(v, md, "", func(fd protoreflect.FieldDescriptor, fieldPath string, parent map[string]interface{}, key string) bool {
+		if r.redacts(fd, fieldPath) {
+			parent[key] = redactedMarker
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"strings"
+	"testing"
+)
+
+const testAccountJSON = `{
+	"userName": "jane",
+	"password": "hunter2",
+	"tags": ["a", "b"],
+	"address": {"street": "1 Main St", "city": "Paris", "next": {"street": "2 Side St", "city": "Lyon"}},
+	"labels": {"team": "billing"}
+}`
+
+func TestEncodePayload_Redaction(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []Option
+		redacted []string
+		kept     []string
+	}{
+		{
+			name:     "debug_redact",
+			options:  []Option{},
+			redacted: []string{"hunter2"},
+			kept:     []string{"jane", "1 Main St", "2 Side St", "billing"},
+		},
+		{
+			name:     "field path",
+			options:  []Option{WithRedactFieldPaths("address.street")},
+			redacted: []string{"hunter2", "1 Main St"},
+			kept:     []string{"jane", "2 Side St", "Paris"},
+		},
+		{
+			name:     "field path glob",
+			options:  []Option{WithRedactFieldPaths("address.*.street")},
+			redacted: []string{"hunter2", "2 Side St"},
+			kept:     []string{"1 Main St"},
+		},
+		{
+			name:     "field name anywhere",
+			options:  []Option{WithRedactFieldNames("STREET")},
+			redacted: []string{"hunter2", "1 Main St", "2 Side St"},
+			kept:     []string{"Paris", "Lyon"},
+		},
+		{
+			name:     "field name pattern",
+			options:  []Option{WithRedactFieldNames("user*", "labels")},
+			redacted: []string{"hunter2", "jane", "billing"},
+			kept:     []string{"Paris"},
+		},
+		{
+			name:     "proto names",
+			options:  []Option{WithBodyProtoNames(true), WithRedactFieldNames("user_name")},
+			redacted: []string{"hunter2", "jane"},
+			kept:     []string{"Paris"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := encodePayload("/test.Service/Method", newTestAccount(t, testAccountJSON), newConfig(tt.options))
+
+			if !strings.Contains(payload, redactedMarker) {
+				t.Errorf("Expected payload to contain %q, got %s", redactedMarker, payload)
+			}
+			for _, s := range tt.redacted {
+				if strings.Contains(payload, s) {
+					t.Errorf("Expected %q to be redacted, got %s", s, payload)
+				}
+			}
+			for _, s := range tt.kept {
+				if !strings.Contains(payload, s) {
+					t.Errorf("Expected %q to be kept, got %s", s, payload)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodePayload_Scrubber(t *testing.T) {
+	var method string
+	scrubber := func(fullMethod string, payload interface{}) interface{} {
+		method = fullMethod
+		if m, ok := payload.(map[string]interface{}); ok {
+			delete(m, "userName")
+			return m
+		}
+		return strings.ReplaceAll(payload.(string), "secret", "******")
+	}
+	o := newConfig([]Option{WithPayloadScrubber(scrubber)})
+
+	payload := encodePayload("/test.Service/Method", newTestAccount(t, testAccountJSON), o)
+	if strings.Contains(payload, "jane") {
+		t.Errorf("Expected scrubber to remove the user name, got %s", payload)
+	}
+	if strings.Contains(payload, "hunter2") {
+		t.Errorf("Expected built-in redaction to run before the scrubber, got %s", payload)
+	}
+	if method != "/test.Service/Method" {
+		t.Errorf("Expected scrubber to receive the method, got %q", method)
+	}
+
+	if got := encodePayload("/test.Service/Method", "a secret", o); got != "a ******" {
+		t.Errorf("Expected scrubber to apply to non-proto payloads, got %q", got)
+	}
+}
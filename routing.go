@@ -0,0 +1,146 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/metadata"
+)
+
+// ClientRouter picks the client an RPC handled by the server interceptors reports through. Returning nil keeps the
+// client of the hub of the call.
+type ClientRouter func(ctx context.Context, fullMethod string, md metadata.MD) *sentry.Client
+
+// Clients lazily creates one client per DSN, sharing the same options otherwise, and keeps them for the lifetime of
+// the process. Call Shutdown when the process stops so that pending events are delivered.
+type Clients struct {
+	options sentry.ClientOptions
+
+	mu      sync.Mutex
+	clients map[string]*clientEntry
+}
+
+type clientEntry struct {
+	client *sentry.Client
+	err    error
+}
+
+// NewClients returns a cache of clients created with the options, in which only the DSN differs.
+func NewClients(options sentry.ClientOptions) *Clients {
+	return &Clients{options: options, clients: make(map[string]*clientEntry)}
+}
+
+// Client returns the client of the DSN, creating it on first use. A DSN that failed to create a client keeps
+// returning the same error.
+func (c *Clients) Client(dsn string) (*sentry.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.clients[dsn]
+	if !ok {
+		options := c.options
+		options.Dsn = dsn
+		entry = &clientEntry{}
+		entry.client, entry.err = sentry.NewClient(options)
+		c.clients[dsn] = entry
+	}
+	return entry.client, entry.err
+}
+
+// Flush waits until the events of every client created so far are delivered or the timeout is reached. It reports
+// whether all of them were delivered.
+func (c *Clients) Flush(timeout time.Duration) bool {
+	clients := c.all()
+
+	var wg sync.WaitGroup
+	results := make(chan bool, len(clients))
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *sentry.Client) {
+			defer wg.Done()
+			results <- client.Flush(timeout)
+		}(client)
+	}
+	wg.Wait()
+	close(results)
+
+	flushed := true
+	for ok := range results {
+		flushed = flushed && ok
+	}
+	return flushed
+}
+
+// Shutdown flushes every client created so far and closes them. It reports whether all events were delivered.
+func (c *Clients) Shutdown(timeout time.Duration) bool {
+	flushed := c.Flush(timeout)
+	for _, client := range c.all() {
+		client.Close()
+	}
+	return flushed
+}
+
+func (c *Clients) all() []*sentry.Client {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	clients := make([]*sentry.Client, 0, len(c.clients))
+	for _, entry := range c.clients {
+		if entry.client != nil {
+			clients = append(clients, entry.client)
+		}
+	}
+	return clients
+}
+
+// route returns a ClientRouter reporting through the client of the DSN mapped to the key of each RPC.
+func (c *Clients) route(dsns map[string]string, key func(ctx context.Context, fullMethod string, md metadata.MD) string) ClientRouter {
+	routes := make(map[string]string, len(dsns))
+	for k, dsn := range dsns {
+		routes[k] = dsn
+	}
+
+	return func(ctx context.Context, fullMethod string, md metadata.MD) *sentry.Client {
+		dsn, ok := routes[key(ctx, fullMethod, md)]
+		if !ok {
+			return nil
+		}
+		client, err := c.Client(dsn)
+		if err != nil {
+			sentry.DebugLogger.Printf("grpc_sentry: cannot create the client routed to %q: %v", dsn, err)
+			return nil
+		}
+		return client
+	}
+}
+
+// RouteByService returns a ClientRouter reporting RPCs through the DSN mapped to their service name, e.g.
+// "billing.v1.Invoices". RPCs of other services keep the client of their hub.
+func RouteByService(clients *Clients, dsns map[string]string) ClientRouter {
+	return clients.route(dsns, func(_ context.Context, fullMethod string, _ metadata.MD) string {
+		service, _ := splitMethod(fullMethod)
+		return service
+	})
+}
+
+// RouteByMetadata returns a ClientRouter reporting RPCs through the DSN mapped to the value of an incoming metadata
+// key, e.g. "x-tenant-id". RPCs without the key, or with an unknown value, keep the client of their hub.
+func RouteByMetadata(clients *Clients, key string, dsns map[string]string) ClientRouter {
+	return clients.route(dsns, func(_ context.Context, _ string, md metadata.MD) string {
+		return firstValue(md, key)
+	})
+}
+
+// routeHub binds the client picked by the configured ClientRouter to the hub of the call.
+func routeHub(ctx context.Context, hub *sentry.Hub, o *options, fullMethod string) {
+	if o.ClientRouter == nil {
+		return
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if client := o.ClientRouter(ctx, fullMethod, md); client != nil {
+		hub.BindClient(client)
+	}
+}
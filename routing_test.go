@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	billingDSN = "https://billing@test.ingest.sentry.io/1"
+	searchDSN  = "https://search@test.ingest.sentry.io/2"
+)
+
+func newTestClients() (*Clients, *recordingTransport) {
+	transport := &recordingTransport{}
+	return NewClients(sentry.ClientOptions{Transport: transport}), transport
+}
+
+func TestClients_Client(t *testing.T) {
+	clients, _ := newTestClients()
+
+	first, err := clients.Client(billingDSN)
+	if err != nil {
+		t.Fatalf("Expected client to be created, got %v", err)
+	}
+	if first.Options().Dsn != billingDSN {
+		t.Errorf("Expected client for %s, got %s", billingDSN, first.Options().Dsn)
+	}
+	if second, _ := clients.Client(billingDSN); second != first {
+		t.Error("Expected the client of a DSN to be cached")
+	}
+	if other, _ := clients.Client(searchDSN); other == first {
+		t.Error("Expected a client per DSN")
+	}
+
+	if _, err := clients.Client("not a dsn"); err == nil {
+		t.Error("Expected an error for an invalid DSN")
+	}
+	if len(clients.all()) != 2 {
+		t.Errorf("Expected only valid clients to be kept, got %d", len(clients.all()))
+	}
+	if !clients.Shutdown(time.Second) {
+		t.Error("Expected all clients to be flushed")
+	}
+}
+
+func TestRouteByService(t *testing.T) {
+	clients, _ := newTestClients()
+	router := RouteByService(clients, map[string]string{
+		"billing.v1.Invoices": billingDSN,
+		"search.Search":       searchDSN,
+		"broken.Service":      "not a dsn",
+	})
+
+	tests := []struct {
+		fullMethod string
+		expected   string
+	}{
+		{"/billing.v1.Invoices/Get", billingDSN},
+		{"/search.Search/Query", searchDSN},
+		{"/other.Service/Method", ""},
+		{"/broken.Service/Method", ""},
+	}
+
+	for _, tt := range tests {
+		client := router(context.Background(), tt.fullMethod, nil)
+		if got := dsnOf(client); got != tt.expected {
+			t.Errorf("Expected %s to be routed to %q, got %q", tt.fullMethod, tt.expected, got)
+		}
+	}
+}
+
+func TestRouteByMetadata(t *testing.T) {
+	clients, _ := newTestClients()
+	router := RouteByMetadata(clients, "x-tenant-id", map[string]string{"acme": billingDSN})
+
+	if got := dsnOf(router(context.Background(), "/test.Service/Method", metadata.Pairs("x-tenant-id", "acme"))); got != billingDSN {
+		t.Errorf("Expected the acme tenant to be routed to %s, got %q", billingDSN, got)
+	}
+	if got := dsnOf(router(context.Background(), "/test.Service/Method", metadata.Pairs("x-tenant-id", "globex"))); got != "" {
+		t.Errorf("Expected unknown tenants not to be routed, got %q", got)
+	}
+	if got := dsnOf(router(context.Background(), "/test.Service/Method", nil)); got != "" {
+		t.Errorf("Expected calls without tenant not to be routed, got %q", got)
+	}
+}
+
+func TestUnaryServerInterceptor_ClientRouter(t *testing.T) {
+	hub, defaultTransport := newRecordingHub(t)
+	clients, transport := newTestClients()
+	defer clients.Shutdown(time.Second)
+
+	interceptor := UnaryServerInterceptor(
+		WithClientRouter(RouteByMetadata(clients, "x-tenant-id", map[string]string{"acme": billingDSN})),
+	)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+
+	for _, tenant := range []string{"acme", "globex"} {
+		ctx := sentry.SetHubOnContext(context.Background(), hub)
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("x-tenant-id", tenant))
+
+		var routed string
+		handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+			routed = dsnOf(sentry.GetHubFromContext(ctx).Client())
+			return nil, status.Error(codes.Internal, "boom")
+		}
+		_, _ = interceptor(ctx, "request", info, handler)
+
+		if tenant == "acme" && routed != billingDSN {
+			t.Errorf("Expected the acme tenant to report through %s, got %q", billingDSN, routed)
+		}
+		if tenant == "globex" && routed == billingDSN {
+			t.Error("Expected the globex tenant to keep the default client")
+		}
+	}
+
+	if len(transport.Errors()) != 1 || len(transport.Transactions()) != 1 {
+		t.Errorf("Expected the acme event and transaction through the routed client, got %d and %d",
+			len(transport.Errors()), len(transport.Transactions()))
+	}
+	if len(defaultTransport.Errors()) != 1 {
+		t.Errorf("Expected the globex event through the default client, got %d", len(defaultTransport.Errors()))
+	}
+}
+
+func dsnOf(client *sentry.Client) string {
+	if client == nil {
+		return ""
+	}
+	return client.Options().Dsn
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/hex"
 	"regexp"
+	"time"
 
 	"github.com/getsentry/sentry-go"
 	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
@@ -16,15 +17,29 @@ import (
 	"google.golang.org/grpc"
 )
 
-func recoverWithSentry(hub *sentry.Hub, ctx context.Context, o *options) {
-	if err := recover(); err != nil {
-		eventID := hub.RecoverWithContext(ctx, err)
+// recoverWithSentry reports a recovered panic to Sentry and replaces the result of the call with the error built by
+// the configured RecoveryHandler, so that clients never see an OK status for a call that panicked.
+func recoverWithSentry(hub *sentry.Hub,
+	ctx context.Context,
+	o *options,
+	fullMethod string,
+	span *sentry.Span,
+	err *error) {
+
+	if p := recover(); p != nil {
+		eventID := capturePanic(ctx, hub, o, fullMethod, p)
 		if eventID != nil && o.WaitForDelivery {
 			hub.Flush(o.Timeout)
 		}
 
+		*err = o.RecoveryHandler(ctx, p, eventID)
+		span.Status = toSpanStatus(status.Code(*err))
+
+		// Always sample when a panic has occurred.
+		span.Sampled = sentry.SampledTrue
+
 		if o.Repanic {
-			panic(err)
+			panic(p)
 		}
 	}
 }
@@ -34,13 +49,18 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 	return func(ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
-		handler grpc.UnaryHandler) (interface{}, error) {
+		handler grpc.UnaryHandler) (resp interface{}, err error) {
 
-		hub := sentry.GetHubFromContext(ctx)
-		if hub == nil {
-			hub = sentry.CurrentHub().Clone()
-			ctx = sentry.SetHubOnContext(ctx, hub)
+		if !o.methods.instrumented(info.FullMethod) {
+			return handler(ctx, req)
 		}
+		o := o.forMethod(info.FullMethod)
+		start := time.Now()
+
+		hub, events, ctx := hubForCall(ctx, o)
+		routeHub(ctx, hub, o, info.FullMethod)
+		attachCallDetails(events, serverCallDetails(ctx, info.FullMethod, callTypeUnary, o))
+		events.addEventProcessor(addStatusDetails)
 
 		operationName := defaultServerOperationName
 		if o.OperationNameOverride != "" {
@@ -48,35 +68,33 @@ func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
 		}
 
 		md, _ := metadata.FromIncomingContext(ctx) // nil check in ContinueFromGrpcMetadata
+		attachUser(ctx, hub.Scope(), o, md)
 
-		// Use the FullMethod as transaction name and as description. This way the FullMethod will show up under
-		// the span, and under the transaction.
+		// Use the FullMethod as description, so that it shows up under the span whatever the transaction is named.
 		tx := sentry.StartTransaction(
 			ctx,
-			info.FullMethod,
-			sentry.WithOpName(operationName),
-			sentry.WithDescription(info.FullMethod),
-			sentry.WithTransactionSource(sentry.SourceURL),
-			ContinueFromGrpcMetadata(md),
+			o.TransactionNamer(info.FullMethod),
+			transactionOptions(operationName, info.FullMethod, md, o.TransactionSource)...,
 		)
 		tx.SetData("grpc.request.method", info.FullMethod)
+		recordMetadata(hub.Scope(), tx, o, md)
 		ctx = tx.Context()
 		defer tx.Finish()
 
 		if o.CaptureRequestBody {
-			// TODO: Perhaps makes sense to use SetRequestBody instead?
-			hub.Scope().SetExtra("requestBody", req)
+			attachRequestBody(events, info.FullMethod, req, o)
 		}
-		defer recoverWithSentry(hub, ctx, o)
-
-		resp, err := handler(ctx, req)
-		if err != nil && o.ReportOn(err) {
-			tags := grpc_tags.Extract(ctx)
-			for k, v := range tags.Values() {
-				hub.Scope().SetTag(k, v.(string))
+		defer recoverWithSentry(hub, ctx, o, info.FullMethod, tx, &err)
+
+		resp, err = handler(ctx, req)
+		call := ReportInfo{FullMethod: info.FullMethod, Request: req, Duration: time.Since(start)}
+		if o.shouldReport(ctx, call, err) {
+			setTags(hub.Scope(), o, grpc_tags.Extract(ctx).Values())
+			if o.CaptureResponseBody && hasResponse(resp) {
+				attachResponseBody(events, info.FullMethod, resp, o)
 			}
 
-			hub.CaptureException(err)
+			captureError(ctx, hub, o, info.FullMethod, err)
 
 			// Always sample when an error has occurred.
 			tx.Sampled = sentry.SampledTrue
@@ -92,14 +110,20 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 	return func(srv interface{},
 		ss grpc.ServerStream,
 		info *grpc.StreamServerInfo,
-		handler grpc.StreamHandler) error {
+		handler grpc.StreamHandler) (err error) {
 
-		ctx := ss.Context()
-		hub := sentry.GetHubFromContext(ctx)
-		if hub == nil {
-			hub = sentry.CurrentHub().Clone()
-			ctx = sentry.SetHubOnContext(ctx, hub)
+		if !o.methods.instrumented(info.FullMethod) {
+			return handler(srv, ss)
 		}
+		o := o.forMethod(info.FullMethod)
+		start := time.Now()
+
+		ctx := ss.Context()
+		hub, events, ctx := hubForCall(ctx, o)
+		routeHub(ctx, hub, o, info.FullMethod)
+		callType := callTypeOf(info.IsClientStream, info.IsServerStream)
+		attachCallDetails(events, serverCallDetails(ctx, info.FullMethod, callType, o))
+		events.addEventProcessor(addStatusDetails)
 
 		operationName := defaultServerOperationName
 		if o.OperationNameOverride != "" {
@@ -107,34 +131,32 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 		}
 
 		md, _ := metadata.FromIncomingContext(ctx) // nil check in ContinueFromGrpcMetadata
+		attachUser(ctx, hub.Scope(), o, md)
 
-		// Use the FullMethod as transaction name and as description. This way the FullMethod will show up under
-		// the span, and under the transaction.
+		// Use the FullMethod as description, so that it shows up under the span whatever the transaction is named.
 		tx := sentry.StartTransaction(
 			ctx,
-			info.FullMethod,
-			sentry.WithOpName(operationName),
-			sentry.WithDescription(info.FullMethod),
-			sentry.WithTransactionSource(sentry.SourceURL),
-			ContinueFromGrpcMetadata(md),
+			o.TransactionNamer(info.FullMethod),
+			transactionOptions(operationName, info.FullMethod, md, o.TransactionSource)...,
 		)
 		tx.SetData("grpc.request.method", info.FullMethod)
+		recordMetadata(hub.Scope(), tx, o, md)
 		ctx = tx.Context()
 		defer tx.Finish()
 
-		stream := grpc_middleware.WrapServerStream(ss)
-		stream.WrappedContext = ctx
+		wrapped := grpc_middleware.WrapServerStream(ss)
+		wrapped.WrappedContext = ctx
+		stream := newServerStream(wrapped, hub, tx, o, info.FullMethod, start)
+		defer stream.messages.finish()
 
-		defer recoverWithSentry(hub, ctx, o)
+		defer recoverWithSentry(hub, ctx, o, info.FullMethod, tx, &err)
 
-		err := handler(srv, stream)
-		if err != nil && o.ReportOn(err) {
-			tags := grpc_tags.Extract(ctx)
-			for k, v := range tags.Values() {
-				hub.Scope().SetTag(k, v.(string))
-			}
+		err = handler(srv, stream)
+		call := ReportInfo{FullMethod: info.FullMethod, Duration: time.Since(start)}
+		if o.shouldReport(ctx, call, err) {
+			setTags(hub.Scope(), o, grpc_tags.Extract(ctx).Values())
 
-			hub.CaptureException(err)
+			captureError(ctx, hub, o, info.FullMethod, err)
 
 			// Always sample when an error has occurred.
 			tx.Sampled = sentry.SampledTrue
@@ -145,6 +167,23 @@ func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
 	}
 }
 
+// transactionOptions returns the span options used to start a server transaction. The trace is only continued
+// when the incoming metadata carries one, since sentry.StartTransaction does not accept nil options.
+func transactionOptions(operationName, fullMethod string,
+	md metadata.MD,
+	source sentry.TransactionSource) []sentry.SpanOption {
+
+	opts := []sentry.SpanOption{
+		sentry.WithOpName(operationName),
+		sentry.WithDescription(fullMethod),
+		sentry.WithTransactionSource(source),
+	}
+	if continueFrom := ContinueFromGrpcMetadata(md); continueFrom != nil {
+		opts = append(opts, continueFrom)
+	}
+	return opts
+}
+
 // ContinueFromGrpcMetadata returns a span option that updates the span to continue
 // an existing trace. If it cannot detect an existing trace in the request, the
 // span will be left unchanged.
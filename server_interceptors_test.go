@@ -3,13 +3,19 @@ package grpc_sentry
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/getsentry/sentry-go"
+	grpc_tags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 // mockUnaryHandler is a mock handler for testing unary interceptors
@@ -283,3 +289,333 @@ func TestUpdateFromSentryTrace(t *testing.T) {
 		})
 	}
 }
+
+func TestUnaryServerInterceptor_PanicReturnsStatusError(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryServerInterceptor()
+	handler := &mockUnaryHandler{panic: true}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Panic"}
+
+	resp, err := interceptor(ctx, "request", info, handler.handle)
+	if resp != nil {
+		t.Errorf("Expected nil response after panic, got %v", resp)
+	}
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected error with code %s, got %v", codes.Internal, err)
+	}
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event to be captured, got %d", len(events))
+	}
+	if !strings.Contains(status.Convert(err).Message(), string(events[0].EventID)) {
+		t.Errorf("Expected status message to contain event ID %s, got %q", events[0].EventID, status.Convert(err).Message())
+	}
+
+	transactions := transport.Transactions()
+	if len(transactions) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(transactions))
+	}
+	if got := transactions[0].Contexts["trace"]["status"]; got != sentry.SpanStatusInternalError {
+		t.Errorf("Expected transaction status %v, got %v", sentry.SpanStatusInternalError, got)
+	}
+}
+
+func TestStreamServerInterceptor_PanicReturnsStatusError(t *testing.T) {
+	hub, _ := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := StreamServerInterceptor()
+	handler := &mockStreamHandler{panic: true}
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/PanicStream"}
+
+	err := interceptor(nil, &mockServerStream{ctx: ctx}, info, handler.handle)
+	if status.Code(err) != codes.Internal {
+		t.Errorf("Expected error with code %s, got %v", codes.Internal, err)
+	}
+}
+
+func TestUnaryServerInterceptor_RecoveryHandler(t *testing.T) {
+	hub, _ := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	var recovered interface{}
+	interceptor := UnaryServerInterceptor(WithRecoveryHandler(func(_ context.Context, p interface{}, _ *sentry.EventID) error {
+		recovered = p
+		return status.Error(codes.Unavailable, "try again")
+	}))
+	handler := &mockUnaryHandler{panic: true}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Panic"}
+
+	_, err := interceptor(ctx, "request", info, handler.handle)
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("Expected error with code %s, got %v", codes.Unavailable, err)
+	}
+	if recovered != "test panic" {
+		t.Errorf("Expected recovery handler to receive the panic value, got %v", recovered)
+	}
+}
+
+func TestUnaryServerInterceptor_Repanic(t *testing.T) {
+	hub, _ := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryServerInterceptor(WithRepanicOption(true))
+	handler := &mockUnaryHandler{panic: true}
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Panic"}
+
+	defer func() {
+		if p := recover(); p != "test panic" {
+			t.Errorf("Expected interceptor to repanic with the original value, got %v", p)
+		}
+	}()
+	_, _ = interceptor(ctx, "request", info, handler.handle)
+	t.Error("Expected interceptor to repanic")
+}
+
+func TestUnaryServerInterceptor_ScopeIsolation(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	// The clone of a scope shares the processors of the scope it was cloned from, so none may be added to it.
+	for i := 0; i < 3; i++ {
+		hub.Scope().AddEventProcessor(func(event *sentry.Event, hint *sentry.EventHint) *sentry.Event {
+			return event
+		})
+	}
+	interceptor := UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Isolated"}
+
+	const calls = 50
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprintf("call-%d", i)
+			ctx := sentry.SetHubOnContext(context.Background(), hub)
+			ctx = grpc_tags.SetInContext(ctx, grpc_tags.NewTags())
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				grpc_tags.Extract(ctx).Set("call", id)
+				return nil, status.Error(codes.Internal, id)
+			}
+			_, _ = interceptor(ctx, id, info, handler)
+		}(i)
+	}
+	wg.Wait()
+
+	events := transport.Errors()
+	if len(events) != calls {
+		t.Fatalf("Expected %d events, got %d", calls, len(events))
+	}
+	for _, event := range events {
+		id := event.Exception[len(event.Exception)-1].Value
+		if !strings.Contains(id, event.Tags["call"]) {
+			t.Errorf("Expected tag %q to belong to event %q", event.Tags["call"], id)
+		}
+		if event.Request == nil || event.Request.Data != event.Tags["call"] {
+			t.Errorf("Expected request body %v to belong to event %q", event.Request, id)
+		}
+	}
+
+	// The hub shared through the context must not be touched by any call.
+	hub.CaptureMessage("after")
+	events = transport.Errors()
+	last := events[len(events)-1]
+	if _, ok := last.Tags["call"]; ok {
+		t.Errorf("Expected shared hub to have no call tag, got %q", last.Tags["call"])
+	}
+	if last.Request != nil {
+		t.Errorf("Expected shared hub to have no request body, got %v", last.Request)
+	}
+}
+
+func TestStreamServerInterceptor_ScopeIsolation(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	interceptor := StreamServerInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/test.Service/IsolatedStream"}
+
+	const calls = 50
+	var wg sync.WaitGroup
+	for i := 0; i < calls; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			id := fmt.Sprintf("call-%d", i)
+			ctx := sentry.SetHubOnContext(context.Background(), hub)
+			ctx = grpc_tags.SetInContext(ctx, grpc_tags.NewTags())
+			handler := func(srv interface{}, stream grpc.ServerStream) error {
+				grpc_tags.Extract(stream.Context()).Set("call", id)
+				return status.Error(codes.Internal, id)
+			}
+			_ = interceptor(nil, &mockServerStream{ctx: ctx}, info, handler)
+		}(i)
+	}
+	wg.Wait()
+
+	events := transport.Errors()
+	if len(events) != calls {
+		t.Fatalf("Expected %d events, got %d", calls, len(events))
+	}
+	for _, event := range events {
+		id := event.Exception[len(event.Exception)-1].Value
+		if !strings.Contains(id, event.Tags["call"]) || len(event.Tags["call"]) == 0 {
+			t.Errorf("Expected tag %q to belong to event %q", event.Tags["call"], id)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor_IgnoredMethod(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryServerInterceptor()
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "unhealthy")}
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+
+	if _, err := interceptor(ctx, "request", info, handler.handle); status.Code(err) != codes.Internal {
+		t.Errorf("Expected handler error to be returned, got %v", err)
+	}
+	if n := len(transport.Errors()); n != 0 {
+		t.Errorf("Expected no events for an ignored method, got %d", n)
+	}
+	if n := len(transport.Transactions()); n != 0 {
+		t.Errorf("Expected no transactions for an ignored method, got %d", n)
+	}
+}
+
+func TestUnaryServerInterceptor_MethodOptions(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	interceptor := UnaryServerInterceptor(
+		WithMethodOptions("/search.Search/Query", WithCaptureRequestBody(false)),
+		WithMethodOptions("/billing.*", WithReportOn(ReportOnCodes(codes.Internal))),
+	)
+	handler := &mockUnaryHandler{err: status.Error(codes.NotFound, "missing")}
+
+	_, _ = interceptor(ctx, "query", &grpc.UnaryServerInfo{FullMethod: "/search.Search/Query"}, handler.handle)
+	_, _ = interceptor(ctx, "invoice", &grpc.UnaryServerInfo{FullMethod: "/billing.Invoices/Get"}, handler.handle)
+	_, _ = interceptor(ctx, "other", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 2 {
+		t.Fatalf("Expected billing error to be filtered out, got %d events", len(events))
+	}
+	if events[0].Request != nil && events[0].Request.Data != "" {
+		t.Errorf("Expected no request body for search, got %v", events[0].Request.Data)
+	}
+	if events[1].Request == nil || events[1].Request.Data != "other" {
+		t.Errorf("Expected request body for other methods, got %v", events[1].Request)
+	}
+}
+
+func TestUnaryServerInterceptor_ReportPredicate(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+	var seen ReportInfo
+	interceptor := UnaryServerInterceptor(WithReportPredicate(func(_ context.Context, info ReportInfo, _ error) bool {
+		seen = info
+		return info.FullMethod == "/test.Service/Reported"
+	}))
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "internal")}
+
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Ignored"}, handler.handle)
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Reported"}, handler.handle)
+
+	if n := len(transport.Errors()); n != 1 {
+		t.Errorf("Expected 1 event, got %d", n)
+	}
+	if seen.Request != "request" {
+		t.Errorf("Expected predicate to receive the request, got %v", seen.Request)
+	}
+	if seen.Duration <= 0 {
+		t.Errorf("Expected predicate to receive the call duration, got %v", seen.Duration)
+	}
+}
+
+func TestUnaryServerInterceptor_ResponseBody(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       []Option
+		expectCapture bool
+	}{
+		{
+			name:          "disabled by default",
+			options:       []Option{},
+			expectCapture: false,
+		},
+		{
+			name:          "enabled",
+			options:       []Option{WithCaptureResponseBody(true)},
+			expectCapture: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, transport := newRecordingHub(t)
+			ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+			interceptor := UnaryServerInterceptor(tt.options...)
+			handler := &mockUnaryHandler{
+				response: newTestAccount(t, `{"userName": "jane", "password": "hunter2"}`),
+				err:      status.Error(codes.Internal, "partial failure"),
+			}
+			info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}
+			_, _ = interceptor(ctx, "request", info, handler.handle)
+
+			events := transport.Errors()
+			if len(events) != 1 {
+				t.Fatalf("Expected 1 event, got %d", len(events))
+			}
+			response, ok := events[0].Contexts["response"]
+			if ok != tt.expectCapture {
+				t.Fatalf("Expected response to be captured: %v, got %v", tt.expectCapture, events[0].Contexts["response"])
+			}
+			if !tt.expectCapture {
+				return
+			}
+			data, _ := response["data"].(string)
+			if !strings.Contains(data, "jane") || strings.Contains(data, "hunter2") {
+				t.Errorf("Expected response to be rendered and redacted, got %s", data)
+			}
+			if len(transport.Transactions()) != 1 || transport.Transactions()[0].Contexts["response"] != nil {
+				t.Error("Expected the response not to be attached to the transaction")
+			}
+		})
+	}
+}
+
+func TestUnaryServerInterceptor_NilResponseBody(t *testing.T) {
+	tests := []struct {
+		name     string
+		response interface{}
+	}{
+		{name: "nil", response: nil},
+		{name: "typed nil message", response: (*wrapperspb.StringValue)(nil)},
+		{name: "typed nil map", response: map[string]string(nil)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, transport := newRecordingHub(t)
+			ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+			interceptor := UnaryServerInterceptor(WithCaptureResponseBody(true))
+			handler := &mockUnaryHandler{response: tt.response, err: status.Error(codes.Internal, "internal")}
+			_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+			events := transport.Errors()
+			if len(events) != 1 {
+				t.Fatalf("Expected 1 event, got %d", len(events))
+			}
+			if response, ok := events[0].Contexts["response"]; ok {
+				t.Errorf("Expected no response to be attached, got %v", response)
+			}
+		})
+	}
+}
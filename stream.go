@@ -0,0 +1,340 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	grpc_middleware "github.com/grpc-ecosystem/go-grpc-middleware"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	directionSend = "send"
+	directionRecv = "recv"
+)
+
+// messageRecorder counts the messages flowing through a stream and, when configured, traces each of them as a
+// child span of the span covering the stream. Bytes are only counted along with message spans, since computing the
+// size of every message is not free.
+type messageRecorder struct {
+	parent *sentry.Span
+	o      *options
+
+	sendAttempts  atomic.Int64
+	recvAttempts  atomic.Int64
+	sent          atomic.Int64
+	received      atomic.Int64
+	bytesSent     atomic.Int64
+	bytesReceived atomic.Int64
+	spans         atomic.Int64
+	spansDropped  atomic.Int64
+}
+
+func newMessageRecorder(parent *sentry.Span, o *options) *messageRecorder {
+	return &messageRecorder{parent: parent, o: o}
+}
+
+// record performs a single SendMsg or RecvMsg call and accounts for it. It returns the index of the message within
+// its direction along with the error of the call.
+func (r *messageRecorder) record(direction string, m interface{}, call func(interface{}) error) (int64, error) {
+	attempts, count, bytes := &r.sendAttempts, &r.sent, &r.bytesSent
+	if direction == directionRecv {
+		attempts, count, bytes = &r.recvAttempts, &r.received, &r.bytesReceived
+	}
+
+	index := attempts.Add(1) - 1
+	span := r.startSpan(direction, index)
+
+	err := call(m)
+
+	var size int
+	if err == nil {
+		count.Add(1)
+		if r.o.StreamMessageSpans {
+			size = messageSize(m)
+			bytes.Add(int64(size))
+		}
+	}
+
+	if span != nil {
+		span.SetData("grpc.message.size", size)
+		if err != io.EOF {
+			span.Status = toSpanStatus(status.Code(err))
+		} else {
+			span.Status = sentry.SpanStatusOK
+		}
+		span.Finish()
+	}
+
+	return index, err
+}
+
+// startSpan returns a child span for the message, or nil when the message is not sampled or the cap on spans per
+// stream has been reached.
+func (r *messageRecorder) startSpan(direction string, index int64) *sentry.Span {
+	if !r.o.StreamMessageSpans {
+		return nil
+	}
+	if r.o.StreamMessageSampleRate < 1 && rand.Float64() >= r.o.StreamMessageSampleRate {
+		return nil
+	}
+	if r.spans.Add(1) > int64(r.o.MaxStreamMessageSpans) {
+		r.spansDropped.Add(1)
+		return nil
+	}
+
+	span := r.parent.StartChild(
+		fmt.Sprintf("%s.%s", r.parent.Op, direction),
+		sentry.WithDescription(fmt.Sprintf("%s message %d", direction, index)),
+	)
+	span.SetData("grpc.message.direction", direction)
+	span.SetData("grpc.message.index", index)
+	return span
+}
+
+// finish sets the message counters on the span covering the stream. It must be called before that span is finished.
+func (r *messageRecorder) finish() {
+	r.parent.SetData("grpc.stream.messages_sent", r.sent.Load())
+	r.parent.SetData("grpc.stream.messages_received", r.received.Load())
+	if r.o.StreamMessageSpans {
+		r.parent.SetData("grpc.stream.bytes_sent", r.bytesSent.Load())
+		r.parent.SetData("grpc.stream.bytes_received", r.bytesReceived.Load())
+	}
+	if dropped := r.spansDropped.Load(); dropped > 0 {
+		r.parent.SetData("grpc.stream.message_spans_dropped", dropped)
+	}
+}
+
+// messageSize returns the wire size of a protobuf message, or zero for any other value.
+func messageSize(m interface{}) int {
+	if msg, ok := m.(proto.Message); ok {
+		return proto.Size(msg)
+	}
+	return 0
+}
+
+// messageLog keeps copies of the first messages sent on a stream so that they can be attached to reported errors.
+type messageLog struct {
+	mu       sync.Mutex
+	max      int
+	messages []interface{}
+}
+
+// add records a copy of m unless the log is already full. Messages are copied since callers may reuse them.
+func (l *messageLog) add(m interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if len(l.messages) >= l.max {
+		return
+	}
+	if msg, ok := m.(proto.Message); ok {
+		m = proto.Clone(msg)
+	}
+	l.messages = append(l.messages, m)
+}
+
+// snapshot returns the messages recorded so far.
+func (l *messageLog) snapshot() []interface{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return append([]interface{}(nil), l.messages...)
+}
+
+// clientStream wraps a grpc.ClientStream so that the span started by StreamClientInterceptor covers the whole
+// lifetime of the stream rather than only its creation. The span is finished once the stream ends, either because
+// the server closed it, a message failed, or the context of the call was done.
+type clientStream struct {
+	grpc.ClientStream
+
+	ctx      context.Context
+	desc     *grpc.StreamDesc
+	hub      *sentry.Hub
+	events   *callEvents
+	span     *sentry.Span
+	o        *options
+	messages *messageRecorder
+	sent     *messageLog
+	method   string
+	start    time.Time
+
+	done       chan struct{}
+	finishOnce sync.Once
+}
+
+func newClientStream(ctx context.Context,
+	s grpc.ClientStream,
+	desc *grpc.StreamDesc,
+	hub *sentry.Hub,
+	events *callEvents,
+	span *sentry.Span,
+	o *options,
+	method string,
+	start time.Time) *clientStream {
+
+	cs := &clientStream{
+		ClientStream: s,
+		ctx:          ctx,
+		desc:         desc,
+		hub:          hub,
+		events:       events,
+		span:         span,
+		o:            o,
+		messages:     newMessageRecorder(span, o),
+		sent:         &messageLog{max: o.MaxCapturedStreamMessages},
+		method:       method,
+		start:        start,
+		done:         make(chan struct{}),
+	}
+
+	if o.CaptureRequestBody {
+		attachStreamRequestBody(events, method, cs.sent, o)
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			cs.finish(status.FromContextError(ctx.Err()).Err())
+		case <-cs.done:
+		}
+	}()
+
+	return cs
+}
+
+func (s *clientStream) Header() (metadata.MD, error) {
+	md, err := s.ClientStream.Header()
+	if err != nil {
+		s.finish(err)
+	}
+	return md, err
+}
+
+func (s *clientStream) SendMsg(m interface{}) error {
+	if s.o.CaptureRequestBody {
+		s.sent.add(m)
+	}
+	_, err := s.messages.record(directionSend, m, s.ClientStream.SendMsg)
+	// On io.EOF the stream was terminated by the server and the actual status is returned by RecvMsg.
+	if err != nil && err != io.EOF {
+		s.finish(err)
+	}
+	return err
+}
+
+func (s *clientStream) RecvMsg(m interface{}) error {
+	_, err := s.messages.record(directionRecv, m, s.ClientStream.RecvMsg)
+	switch {
+	case err == io.EOF:
+		s.finish(nil)
+	case err != nil:
+		s.finish(err)
+		call := ReportInfo{FullMethod: s.method, Duration: time.Since(s.start)}
+		if s.o.shouldReport(s.ctx, call, err) {
+			trailer := s.ClientStream.Trailer()
+			if capturesResponseMetadata(s.o) {
+				header, _ := s.ClientStream.Header()
+				attachResponseMetadata(s.hub.Scope(), s.events, s.o, header, trailer)
+			}
+			attachDownstreamStatus(s.events, trailer)
+			captureError(s.ctx, s.hub, s.o, s.method, err)
+		}
+	case !s.desc.ServerStreams:
+		// Without server streaming there is exactly one response, after which the call is complete.
+		s.finish(nil)
+	}
+	return err
+}
+
+// finish records the final status of the stream on its span and finishes it, at most once.
+func (s *clientStream) finish(err error) {
+	s.finishOnce.Do(func() {
+		close(s.done)
+		s.messages.finish()
+		s.span.Status = toSpanStatus(status.Code(err))
+		s.span.Finish()
+	})
+}
+
+// serverStream wraps the stream handed to the handler by StreamServerInterceptor so that individual messages are
+// counted, optionally traced, and failures are visible even when the handler does not propagate them.
+type serverStream struct {
+	*grpc_middleware.WrappedServerStream
+
+	hub      *sentry.Hub
+	o        *options
+	messages *messageRecorder
+	method   string
+	start    time.Time
+}
+
+func newServerStream(s *grpc_middleware.WrappedServerStream,
+	hub *sentry.Hub,
+	tx *sentry.Span,
+	o *options,
+	method string,
+	start time.Time) *serverStream {
+
+	return &serverStream{
+		WrappedServerStream: s,
+		hub:                 hub,
+		o:                   o,
+		messages:            newMessageRecorder(tx, o),
+		method:              method,
+		start:               start,
+	}
+}
+
+func (s *serverStream) SendMsg(m interface{}) error {
+	index, err := s.messages.record(directionSend, m, s.WrappedServerStream.SendMsg)
+	if err != nil {
+		s.recordMessageError(directionSend, index, err)
+	}
+	return err
+}
+
+func (s *serverStream) RecvMsg(m interface{}) error {
+	index, err := s.messages.record(directionRecv, m, s.WrappedServerStream.RecvMsg)
+	// io.EOF only signals that the client has finished sending.
+	if err != nil && err != io.EOF {
+		s.recordMessageError(directionRecv, index, err)
+	}
+	return err
+}
+
+// recordMessageError leaves a breadcrumb for a failed message and, when configured, captures the error on its own.
+func (s *serverStream) recordMessageError(direction string, index int64, err error) {
+	if s.o.StreamErrorBreadcrumbs {
+		s.hub.AddBreadcrumb(&sentry.Breadcrumb{
+			Type:     "error",
+			Category: "grpc.stream",
+			Level:    sentry.LevelError,
+			Message:  fmt.Sprintf("%s of message %d failed: %v", direction, index, err),
+			Data: map[string]interface{}{
+				"direction": direction,
+				"index":     index,
+				"code":      status.Code(err).String(),
+			},
+		}, nil)
+	}
+
+	call := ReportInfo{FullMethod: s.method, Duration: time.Since(s.start)}
+	if s.o.CaptureStreamErrors && s.o.shouldReport(s.Context(), call, err) {
+		// SendMsg and RecvMsg may run concurrently, so the message details go on a clone rather than a pushed scope.
+		hub := s.hub.Clone()
+		hub.Scope().SetTag("grpc.stream.direction", direction)
+		hub.Scope().SetExtra("grpc.stream.index", index)
+		captureError(s.Context(), hub, s.o, s.method, err)
+	}
+}
@@ -0,0 +1,385 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// scriptedClientStream is a grpc.ClientStream whose RecvMsg returns the scripted errors in order
+type scriptedClientStream struct {
+	mockClientStream
+	recvErrs []error
+	sendErr  error
+}
+
+func (s *scriptedClientStream) SendMsg(interface{}) error { return s.sendErr }
+
+func (s *scriptedClientStream) RecvMsg(interface{}) error {
+	if len(s.recvErrs) == 0 {
+		return nil
+	}
+	err := s.recvErrs[0]
+	s.recvErrs = s.recvErrs[1:]
+	return err
+}
+
+func startClientStream(t *testing.T, ctx context.Context, desc *grpc.StreamDesc, cs grpc.ClientStream, opts ...Option) (grpc.ClientStream, *recordingTransport) {
+	t.Helper()
+
+	hub, transport := newRecordingHub(t)
+	ctx = sentry.SetHubOnContext(ctx, hub)
+
+	interceptor := StreamClientInterceptor(opts...)
+	streamer := &mockStreamer{clientStream: cs}
+	stream, err := interceptor(ctx, desc, nil, "/test.Service/Stream", streamer.stream)
+	if err != nil {
+		t.Fatalf("Expected stream to be created, got %v", err)
+	}
+	return stream, transport
+}
+
+func TestClientStream_SpanLivesUntilEOF(t *testing.T) {
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	cs := &scriptedClientStream{recvErrs: []error{nil, nil, io.EOF}}
+	stream, transport := startClientStream(t, context.Background(), desc, cs)
+
+	for i := 0; i < 2; i++ {
+		if err := stream.RecvMsg(nil); err != nil {
+			t.Fatalf("Expected message %d to be received, got %v", i, err)
+		}
+		if n := len(transport.Transactions()); n != 0 {
+			t.Fatalf("Expected span to be open while the stream is active, got %d finished", n)
+		}
+	}
+
+	if err := stream.RecvMsg(nil); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+	transactions := transport.Transactions()
+	if len(transactions) != 1 {
+		t.Fatalf("Expected span to be finished on io.EOF, got %d finished", len(transactions))
+	}
+	if got := transactions[0].Contexts["trace"]["status"]; got != sentry.SpanStatusOK {
+		t.Errorf("Expected span status %v, got %v", sentry.SpanStatusOK, got)
+	}
+}
+
+func TestClientStream_RecvMsgError(t *testing.T) {
+	tests := []struct {
+		name          string
+		options       []Option
+		expectCapture bool
+	}{
+		{
+			name:          "reported",
+			options:       []Option{},
+			expectCapture: true,
+		},
+		{
+			name:          "filtered by ReportOn",
+			options:       []Option{WithReportOn(ReportOnCodes(codes.Internal))},
+			expectCapture: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			desc := &grpc.StreamDesc{ServerStreams: true}
+			cs := &scriptedClientStream{recvErrs: []error{status.Error(codes.Unavailable, "gone")}}
+			stream, transport := startClientStream(t, context.Background(), desc, cs, tt.options...)
+
+			if err := stream.RecvMsg(nil); status.Code(err) != codes.Unavailable {
+				t.Fatalf("Expected error with code %s, got %v", codes.Unavailable, err)
+			}
+
+			transactions := transport.Transactions()
+			if len(transactions) != 1 {
+				t.Fatalf("Expected span to be finished on error, got %d finished", len(transactions))
+			}
+			if got := transactions[0].Contexts["trace"]["status"]; got != sentry.SpanStatusUnavailable {
+				t.Errorf("Expected span status %v, got %v", sentry.SpanStatusUnavailable, got)
+			}
+			if captured := len(transport.Errors()) == 1; captured != tt.expectCapture {
+				t.Errorf("Expected capture to be %v, got %v", tt.expectCapture, captured)
+			}
+		})
+	}
+}
+
+func TestClientStream_SendMsgError(t *testing.T) {
+	desc := &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+	cs := &scriptedClientStream{sendErr: status.Error(codes.Internal, "broken")}
+	stream, transport := startClientStream(t, context.Background(), desc, cs)
+
+	_ = stream.SendMsg(nil)
+	transactions := transport.Transactions()
+	if len(transactions) != 1 {
+		t.Fatalf("Expected span to be finished on error, got %d finished", len(transactions))
+	}
+	if got := transactions[0].Contexts["trace"]["status"]; got != sentry.SpanStatusInternalError {
+		t.Errorf("Expected span status %v, got %v", sentry.SpanStatusInternalError, got)
+	}
+}
+
+func TestClientStream_SingleResponse(t *testing.T) {
+	desc := &grpc.StreamDesc{ClientStreams: true}
+	cs := &scriptedClientStream{}
+	stream, transport := startClientStream(t, context.Background(), desc, cs)
+
+	if err := stream.RecvMsg(nil); err != nil {
+		t.Fatalf("Expected response to be received, got %v", err)
+	}
+	if n := len(transport.Transactions()); n != 1 {
+		t.Errorf("Expected span to be finished after the single response, got %d finished", n)
+	}
+}
+
+func TestClientStream_ContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	_, transport := startClientStream(t, ctx, desc, &scriptedClientStream{})
+
+	cancel()
+
+	deadline := time.Now().Add(time.Second)
+	for len(transport.Transactions()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	transactions := transport.Transactions()
+	if len(transactions) != 1 {
+		t.Fatalf("Expected span to be finished on cancellation, got %d finished", len(transactions))
+	}
+	if got := transactions[0].Contexts["trace"]["status"]; got != sentry.SpanStatusCanceled {
+		t.Errorf("Expected span status %v, got %v", sentry.SpanStatusCanceled, got)
+	}
+}
+
+// failingServerStream is a grpc.ServerStream whose SendMsg and RecvMsg always fail
+type failingServerStream struct {
+	mockServerStream
+	err error
+}
+
+func (s *failingServerStream) SendMsg(interface{}) error { return s.err }
+func (s *failingServerStream) RecvMsg(interface{}) error { return s.err }
+
+func TestServerStream_MessageErrors(t *testing.T) {
+	tests := []struct {
+		name              string
+		options           []Option
+		expectBreadcrumbs int
+		expectCaptured    int
+	}{
+		{
+			name:              "not instrumented",
+			options:           []Option{},
+			expectBreadcrumbs: 0,
+			expectCaptured:    0,
+		},
+		{
+			name:              "breadcrumbs",
+			options:           []Option{WithStreamErrorBreadcrumbs(true)},
+			expectBreadcrumbs: 3,
+			expectCaptured:    0,
+		},
+		{
+			name:              "breadcrumbs and capture",
+			options:           []Option{WithStreamErrorBreadcrumbs(true), WithCaptureStreamErrors(true)},
+			expectBreadcrumbs: 3,
+			expectCaptured:    3,
+		},
+		{
+			name:              "capture filtered by ReportOn",
+			options:           []Option{WithCaptureStreamErrors(true), WithReportOn(ReportOnCodes(codes.Internal))},
+			expectBreadcrumbs: 0,
+			expectCaptured:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, transport := newRecordingHub(t)
+			ctx := sentry.SetHubOnContext(context.Background(), hub)
+			ss := &failingServerStream{mockServerStream: mockServerStream{ctx: ctx}, err: status.Error(codes.Unavailable, "broken pipe")}
+
+			interceptor := StreamServerInterceptor(tt.options...)
+			info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+			handler := func(srv interface{}, stream grpc.ServerStream) error {
+				// The handler swallows the message errors and fails on its own.
+				_ = stream.SendMsg(nil)
+				_ = stream.SendMsg(nil)
+				_ = stream.RecvMsg(nil)
+				return status.Error(codes.Internal, "handler failed")
+			}
+			_ = interceptor(nil, ss, info, handler)
+
+			events := transport.Errors()
+			if len(events) != tt.expectCaptured+1 {
+				t.Fatalf("Expected %d events, got %d", tt.expectCaptured+1, len(events))
+			}
+
+			final := events[len(events)-1]
+			if len(final.Breadcrumbs) != tt.expectBreadcrumbs {
+				t.Fatalf("Expected %d breadcrumbs, got %d", tt.expectBreadcrumbs, len(final.Breadcrumbs))
+			}
+			if tt.expectBreadcrumbs > 0 {
+				last := final.Breadcrumbs[len(final.Breadcrumbs)-1]
+				if last.Data["direction"] != directionRecv || last.Data["index"] != int64(0) {
+					t.Errorf("Expected breadcrumb for recv of message 0, got %v", last.Data)
+				}
+				second := final.Breadcrumbs[1]
+				if second.Data["direction"] != directionSend || second.Data["index"] != int64(1) {
+					t.Errorf("Expected breadcrumb for send of message 1, got %v", second.Data)
+				}
+			}
+			for _, event := range events[:tt.expectCaptured] {
+				if event.Tags["grpc.stream.direction"] == "" {
+					t.Errorf("Expected captured message error to carry its direction, got %v", event.Tags)
+				}
+			}
+		})
+	}
+}
+
+func TestServerStream_MessageSpansAndCounters(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     []Option
+		expectSpans int
+		expectDrops interface{}
+		expectBytes bool
+	}{
+		{
+			name:        "counters only",
+			options:     []Option{},
+			expectSpans: 0,
+			expectBytes: false,
+		},
+		{
+			name:        "spans",
+			options:     []Option{WithStreamMessageSpans(true)},
+			expectSpans: 5,
+			expectBytes: true,
+		},
+		{
+			name:        "spans capped",
+			options:     []Option{WithStreamMessageSpans(true), WithMaxStreamMessageSpans(2)},
+			expectSpans: 2,
+			expectDrops: int64(3),
+			expectBytes: true,
+		},
+		{
+			name:        "spans never sampled",
+			options:     []Option{WithStreamMessageSpans(true), WithStreamMessageSampleRate(0)},
+			expectSpans: 0,
+			expectBytes: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub, transport := newRecordingHub(t)
+			ctx := sentry.SetHubOnContext(context.Background(), hub)
+
+			interceptor := StreamServerInterceptor(tt.options...)
+			info := &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}
+			handler := func(srv interface{}, stream grpc.ServerStream) error {
+				for i := 0; i < 2; i++ {
+					_ = stream.RecvMsg(&wrapperspb.StringValue{})
+				}
+				for i := 0; i < 3; i++ {
+					_ = stream.SendMsg(wrapperspb.String("hello"))
+				}
+				return nil
+			}
+			if err := interceptor(nil, &mockServerStream{ctx: ctx}, info, handler); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			transactions := transport.Transactions()
+			if len(transactions) != 1 {
+				t.Fatalf("Expected 1 transaction, got %d", len(transactions))
+			}
+			tx := transactions[0]
+			data, _ := tx.Contexts["trace"]["data"].(map[string]interface{})
+			if data["grpc.stream.messages_sent"] != int64(3) {
+				t.Errorf("Expected 3 messages sent, got %v", data["grpc.stream.messages_sent"])
+			}
+			if data["grpc.stream.messages_received"] != int64(2) {
+				t.Errorf("Expected 2 messages received, got %v", data["grpc.stream.messages_received"])
+			}
+			if want := int64(3 * proto.Size(wrapperspb.String("hello"))); tt.expectBytes && data["grpc.stream.bytes_sent"] != want {
+				t.Errorf("Expected %d bytes sent, got %v", want, data["grpc.stream.bytes_sent"])
+			}
+			if _, ok := data["grpc.stream.bytes_sent"]; !tt.expectBytes && ok {
+				t.Errorf("Expected bytes not to be counted without message spans, got %v", data)
+			}
+			if data["grpc.stream.message_spans_dropped"] != tt.expectDrops {
+				t.Errorf("Expected %v dropped spans, got %v", tt.expectDrops, data["grpc.stream.message_spans_dropped"])
+			}
+			if len(tx.Spans) != tt.expectSpans {
+				t.Fatalf("Expected %d message spans, got %d", tt.expectSpans, len(tx.Spans))
+			}
+			if tt.expectSpans > 0 {
+				first := tx.Spans[0]
+				if first.Data["grpc.message.direction"] != directionRecv || first.Data["grpc.message.index"] != int64(0) {
+					t.Errorf("Expected first span to be recv of message 0, got %v", first.Data)
+				}
+			}
+		})
+	}
+}
+
+func TestClientStream_MessageCounters(t *testing.T) {
+	desc := &grpc.StreamDesc{ServerStreams: true}
+	cs := &scriptedClientStream{recvErrs: []error{nil, io.EOF}}
+	stream, transport := startClientStream(t, context.Background(), desc, cs, WithStreamMessageSpans(true))
+
+	_ = stream.SendMsg(wrapperspb.String("hello"))
+	_ = stream.RecvMsg(&wrapperspb.StringValue{})
+	_ = stream.RecvMsg(&wrapperspb.StringValue{})
+
+	transactions := transport.Transactions()
+	if len(transactions) != 1 {
+		t.Fatalf("Expected 1 finished stream span, got %d", len(transactions))
+	}
+	data, _ := transactions[0].Contexts["trace"]["data"].(map[string]interface{})
+	if data["grpc.stream.messages_sent"] != int64(1) || data["grpc.stream.messages_received"] != int64(1) {
+		t.Errorf("Expected 1 message in each direction, got %v", data)
+	}
+	if len(transactions[0].Spans) != 3 {
+		t.Errorf("Expected 3 message spans, got %d", len(transactions[0].Spans))
+	}
+}
+
+func TestClientStream_RequestBody(t *testing.T) {
+	desc := &grpc.StreamDesc{ClientStreams: true, ServerStreams: true}
+	cs := &scriptedClientStream{recvErrs: []error{status.Error(codes.Internal, "internal")}}
+	stream, transport := startClientStream(t, context.Background(), desc, cs, WithMaxCapturedStreamMessages(2))
+
+	msg := wrapperspb.String("first")
+	_ = stream.SendMsg(msg)
+	// Messages are copied, so reusing them does not change what was captured.
+	msg.Value = "second"
+	_ = stream.SendMsg(msg)
+	_ = stream.SendMsg(wrapperspb.String("third"))
+	_ = stream.RecvMsg(nil)
+
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Request == nil {
+		t.Fatalf("Expected 1 event with request data, got %v", events)
+	}
+	if want := `["first","second"]`; events[0].Request.Data != want {
+		t.Errorf("Expected the first sent messages %s, got %s", want, events[0].Request.Data)
+	}
+}
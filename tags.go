@@ -0,0 +1,135 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/getsentry/sentry-go"
+)
+
+const (
+	// maxTagKeyLength is the maximum length of a Sentry tag key.
+	maxTagKeyLength = 32
+
+	// tagsContextName is the context holding the grpc_tags values that cannot be represented as a tag.
+	tagsContextName = "grpc_tags"
+)
+
+// setTags sets the values collected by grpc_tags as tags of the scope. Strings are used as is, while numbers,
+// booleans and other scalar values are formatted; any other value is kept in the grpc_tags context instead, in its
+// JSON form. Nil values, including typed nils, are left out.
+func setTags(scope *sentry.Scope, o *options, values map[string]interface{}) {
+	var complex sentry.Context
+	for k, v := range values {
+		key := sanitizeTagKey(o.TagPrefix + k)
+		if key == "" || isNil(v) {
+			continue
+		}
+		value, ok := tagValue(v)
+		if !ok {
+			if complex == nil {
+				complex = sentry.Context{}
+			}
+			complex[o.TagPrefix+k] = contextValue(v)
+			continue
+		}
+		scope.SetTag(key, truncateTagValue(value, o.MaxTagValueLength))
+	}
+	if complex != nil {
+		scope.SetContext(tagsContextName, complex)
+	}
+}
+
+// tagValue formats the value as a tag value, reporting false when it has no sensible single-line representation.
+func tagValue(v interface{}) (string, bool) {
+	// Errors and Stringers with pointer receivers usually panic when called on a nil pointer.
+	if isNil(v) {
+		return "", false
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, uintptr:
+		return fmt.Sprint(v), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	case []byte:
+		return string(v), utf8.Valid(v)
+	case error:
+		return v.Error(), true
+	case fmt.Stringer:
+		return v.String(), true
+	default:
+		return "", false
+	}
+}
+
+// contextValue returns the JSON representation of v decoded back into plain values, or v formatted with fmt when it
+// cannot be represented as JSON. A single value that fails to marshal would make Sentry drop every context of the
+// event.
+func contextValue(v interface{}) interface{} {
+	data, err := json.Marshal(v)
+	if err == nil {
+		var decoded interface{}
+		if err = json.Unmarshal(data, &decoded); err == nil {
+			return decoded
+		}
+	}
+	return fmt.Sprintf("%+v", v)
+}
+
+// isNil reports whether v is nil or holds a nil pointer, map, slice, function, channel or interface.
+func isNil(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan, reflect.Interface:
+		return rv.IsNil()
+	default:
+		return false
+	}
+}
+
+// sanitizeTagKey makes the key a valid Sentry tag key by replacing unsupported characters with underscores and
+// cutting it to the maximum key length.
+func sanitizeTagKey(key string) string {
+	key = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		case r == '_', r == '.', r == ':', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, strings.TrimSpace(key))
+	if len(key) > maxTagKeyLength {
+		key = key[:maxTagKeyLength]
+	}
+	return key
+}
+
+// truncateTagValue cuts the value to max characters, zero meaning no limit, and strips line breaks which tag values
+// cannot contain.
+func truncateTagValue(value string, max int) string {
+	value = strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+	if max <= 0 || utf8.RuneCountInString(value) <= max {
+		return value
+	}
+	runes := []rune(value)
+	if max <= 3 {
+		return string(runes[:max])
+	}
+	return string(runes[:max-3]) + "..."
+}
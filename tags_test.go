@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	grpc_tags "github.com/grpc-ecosystem/go-grpc-middleware/tags"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// nilStringer panics when String is called on a nil pointer
+type nilStringer struct {
+	name string
+}
+
+func (s *nilStringer) String() string {
+	return s.name
+}
+
+// nilError panics when Error is called on a nil pointer
+type nilError struct {
+	message string
+}
+
+func (e *nilError) Error() string {
+	return e.message
+}
+
+func TestTagValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected string
+		ok       bool
+	}{
+		{"string", "value", "value", true},
+		{"int", 42, "42", true},
+		{"uint64", uint64(7), "7", true},
+		{"float", 1.5, "1.5", true},
+		{"bool", true, "true", true},
+		{"duration", 1500 * time.Millisecond, "1.5s", true},
+		{"error", errors.New("boom"), "boom", true},
+		{"bytes", []byte("abc"), "abc", true},
+		{"struct", struct{ A int }{A: 1}, "", false},
+		{"slice", []string{"a"}, "", false},
+		{"map", map[string]int{"a": 1}, "", false},
+		{"typed nil stringer", (*nilStringer)(nil), "", false},
+		{"typed nil error", (*nilError)(nil), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := tagValue(tt.value)
+			if got != tt.expected || ok != tt.ok {
+				t.Errorf("Expected (%q, %v), got (%q, %v)", tt.expected, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestSanitizeTagKey(t *testing.T) {
+	tests := map[string]string{
+		"grpc.request.id":         "grpc.request.id",
+		"peer address":            "peer_address",
+		"user/id":                 "user_id",
+		"ключ":                    "____",
+		strings.Repeat("k", 40):   strings.Repeat("k", maxTagKeyLength),
+		" padded:key-with_chars ": "padded:key-with_chars",
+	}
+
+	for key, expected := range tests {
+		if got := sanitizeTagKey(key); got != expected {
+			t.Errorf("Expected %q for %q, got %q", expected, key, got)
+		}
+	}
+}
+
+func TestTruncateTagValue(t *testing.T) {
+	tests := []struct {
+		value    string
+		max      int
+		expected string
+	}{
+		{"short", 10, "short"},
+		{"a longer value", 8, "a lon..."},
+		{"unlimited", 0, "unlimited"},
+		{"multi\nline", 20, "multi line"},
+		{"héllo wörld", 6, "hél..."},
+	}
+
+	for _, tt := range tests {
+		if got := truncateTagValue(tt.value, tt.max); got != tt.expected {
+			t.Errorf("Expected %q for %q with max %d, got %q", tt.expected, tt.value, tt.max, got)
+		}
+	}
+}
+
+func TestUnaryServerInterceptor_TypedTags(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	tags := grpc_tags.NewTags().
+		Set("peer.address", "10.0.0.1").
+		Set("retries", 3).
+		Set("cached", false).
+		Set("request", struct{ ID int }{ID: 42}).
+		Set("long", strings.Repeat("x", 300))
+	ctx = grpc_tags.SetInContext(ctx, tags)
+
+	interceptor := UnaryServerInterceptor(WithTagPrefix("grpc."))
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "boom")}
+	_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+	if status.Code(err) != codes.Internal {
+		t.Fatalf("Expected the handler error, got %v", err)
+	}
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	expected := map[string]string{
+		"grpc.peer.address": "10.0.0.1",
+		"grpc.retries":      "3",
+		"grpc.cached":       "false",
+	}
+	for k, v := range expected {
+		if got := events[0].Tags[k]; got != v {
+			t.Errorf("Expected tag %s to be %s, got %s", k, v, got)
+		}
+	}
+	if got := events[0].Tags["grpc.long"]; len(got) != 200 {
+		t.Errorf("Expected long tag to be truncated to 200 characters, got %d", len(got))
+	}
+	if _, ok := events[0].Tags["grpc.request"]; ok {
+		t.Error("Expected complex value not to be a tag")
+	}
+	if _, ok := events[0].Contexts[tagsContextName]["grpc.request"]; !ok {
+		t.Errorf("Expected complex value in the %s context, got %v", tagsContextName, events[0].Contexts)
+	}
+}
+
+func TestUnaryServerInterceptor_TypedNilTags(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	ctx = grpc_tags.SetInContext(ctx, grpc_tags.NewTags().Set("user", (*nilStringer)(nil)))
+
+	interceptor := UnaryServerInterceptor()
+	handler := &mockUnaryHandler{err: status.Error(codes.NotFound, "missing")}
+	_, err := interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("Expected the handler error, got %v", err)
+	}
+
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Level == sentry.LevelFatal {
+		t.Fatalf("Expected 1 event reporting the handler error, got %v", events)
+	}
+	if _, ok := events[0].Tags["user"]; ok {
+		t.Errorf("Expected typed nil value to be left out, got %v", events[0].Tags)
+	}
+}
+
+func TestUnaryServerInterceptor_UnmarshallableTags(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	tags := grpc_tags.NewTags().
+		Set("callback", func() {}).
+		Set("done", make(chan struct{})).
+		Set("lookup", map[interface{}]string{struct{ ID int }{ID: 1}: "one"}).
+		Set("request", struct{ ID int }{ID: 42})
+	ctx = grpc_tags.SetInContext(ctx, tags)
+
+	interceptor := UnaryServerInterceptor()
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "boom")}
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if _, err := json.Marshal(events[0].Contexts); err != nil {
+		t.Errorf("Expected the contexts to marshal to JSON, got %v", err)
+	}
+	values := events[0].Contexts[tagsContextName]
+	for _, key := range []string{"callback", "done", "lookup"} {
+		if _, ok := values[key].(string); !ok {
+			t.Errorf("Expected %s to be formatted, got %v", key, values[key])
+		}
+	}
+	if request, ok := values["request"].(map[string]interface{}); !ok || request["ID"] != float64(42) {
+		t.Errorf("Expected request to keep its structure, got %v", values["request"])
+	}
+}
+
+func TestStreamServerInterceptor_TypedTags(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	ctx = grpc_tags.SetInContext(ctx, grpc_tags.NewTags().Set("retries", 3))
+
+	interceptor := StreamServerInterceptor()
+	handler := &mockStreamHandler{err: status.Error(codes.Internal, "boom")}
+	_ = interceptor(nil, &mockServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: "/test.Service/Stream"}, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 || events[0].Tags["retries"] != "3" {
+		t.Errorf("Expected 1 event with the retries tag, got %v", events)
+	}
+}
@@ -2,6 +2,8 @@
 package grpc_sentry
 
 import (
+	"context"
+	"sync"
 	"testing"
 	"time"
 
@@ -39,3 +41,65 @@ func initSentryForTestWithHub(t *testing.T) *sentry.Hub {
 	hub := sentry.CurrentHub().Clone()
 	return hub
 }
+
+// recordingTransport is a sentry.Transport that keeps every event in memory
+type recordingTransport struct {
+	mu     sync.Mutex
+	events []*sentry.Event
+}
+
+func (r *recordingTransport) Flush(time.Duration) bool              { return true }
+func (r *recordingTransport) FlushWithContext(context.Context) bool { return true }
+func (r *recordingTransport) Configure(sentry.ClientOptions)        {}
+func (r *recordingTransport) Close()                                {}
+func (r *recordingTransport) SendEvent(event *sentry.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+// Errors returns the recorded error events, leaving out transactions.
+func (r *recordingTransport) Errors() []*sentry.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*sentry.Event
+	for _, e := range r.events {
+		if e.Type != "transaction" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// Transactions returns the recorded transaction events.
+func (r *recordingTransport) Transactions() []*sentry.Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []*sentry.Event
+	for _, e := range r.events {
+		if e.Type == "transaction" {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// newRecordingHub returns a hub bound to its own client that records every event sent through it
+func newRecordingHub(t *testing.T) (*sentry.Hub, *recordingTransport) {
+	t.Helper()
+
+	transport := &recordingTransport{}
+	client, err := sentry.NewClient(sentry.ClientOptions{
+		Dsn:              "https://test@test.ingest.sentry.io/123",
+		Transport:        transport,
+		EnableTracing:    true,
+		TracesSampleRate: 1.0,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create Sentry client: %v", err)
+	}
+
+	return sentry.NewHub(client, sentry.NewScope()), transport
+}
@@ -0,0 +1,110 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// UserExtractor identifies the user behind an RPC from its context and incoming metadata. An empty user leaves the
+// events of the call without user.
+type UserExtractor func(ctx context.Context, md metadata.MD) sentry.User
+
+// attachUser sets the user identified by the configured UserExtractor on the scope of the call.
+func attachUser(ctx context.Context, scope *sentry.Scope, o *options, md metadata.MD) {
+	if o.UserExtractor == nil {
+		return
+	}
+	if user := o.UserExtractor(ctx, md); !user.IsEmpty() {
+		scope.SetUser(user)
+	}
+}
+
+// FirstUser returns a UserExtractor returning the first non-empty user identified by the given extractors.
+func FirstUser(extractors ...UserExtractor) UserExtractor {
+	return func(ctx context.Context, md metadata.MD) sentry.User {
+		for _, extract := range extractors {
+			if user := extract(ctx, md); !user.IsEmpty() {
+				return user
+			}
+		}
+		return sentry.User{}
+	}
+}
+
+// UserFromTLSClientCert identifies the user by the subject of the certificate presented by the peer over mutual TLS.
+// The common name is used as username and the first email address of the certificate, if any, as email.
+func UserFromTLSClientCert(ctx context.Context, _ metadata.MD) sentry.User {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return sentry.User{}
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return sentry.User{}
+	}
+
+	cert := info.State.PeerCertificates[0]
+	user := sentry.User{
+		ID:       cert.Subject.String(),
+		Username: cert.Subject.CommonName,
+	}
+	if len(cert.EmailAddresses) > 0 {
+		user.Email = cert.EmailAddresses[0]
+	}
+	return user
+}
+
+// altsAuthInfo is the part of alts.AuthInfo needed to identify the peer, which avoids depending on the ALTS
+// credentials package.
+type altsAuthInfo interface {
+	PeerServiceAccount() string
+}
+
+// UserFromALTS identifies the user by the service account of the peer authenticated with ALTS.
+func UserFromALTS(ctx context.Context, _ metadata.MD) sentry.User {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return sentry.User{}
+	}
+	info, ok := p.AuthInfo.(altsAuthInfo)
+	if !ok || info.PeerServiceAccount() == "" {
+		return sentry.User{}
+	}
+	account := info.PeerServiceAccount()
+	return sentry.User{ID: account, Username: account}
+}
+
+// UserFromJWTClaims identifies the user by the "sub" and "email" claims of the bearer token found in the
+// authorization metadata. The token is decoded but NOT verified, so the user must only be trusted as much as the
+// authentication performed by the server itself; it is meant to label events, not to make decisions.
+func UserFromJWTClaims(_ context.Context, md metadata.MD) sentry.User {
+	authorization := firstValue(md, "authorization")
+	if len(authorization) < len("bearer ") || !strings.EqualFold(authorization[:len("bearer ")], "bearer ") {
+		return sentry.User{}
+	}
+	parts := strings.Split(strings.TrimSpace(authorization[len("bearer "):]), ".")
+	if len(parts) != 3 {
+		return sentry.User{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return sentry.User{}
+	}
+
+	var claims struct {
+		Subject string `json:"sub"`
+		Email   string `json:"email"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return sentry.User{}
+	}
+	return sentry.User{ID: claims.Subject, Email: claims.Email}
+}
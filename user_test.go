@@ -0,0 +1,125 @@
+// SPDX-License-Identifier: Apache-2.0
+package grpc_sentry
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"testing"
+
+	"github.com/getsentry/sentry-go"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func testJWT(claims string) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"HS256"}`)) + "." + encode([]byte(claims)) + ".signature"
+}
+
+type fakeALTSAuthInfo struct {
+	credentials.CommonAuthInfo
+	account string
+}
+
+func (fakeALTSAuthInfo) AuthType() string             { return "alts" }
+func (f fakeALTSAuthInfo) PeerServiceAccount() string { return f.account }
+
+func TestUserFromJWTClaims(t *testing.T) {
+	tests := []struct {
+		name          string
+		authorization string
+		expected      sentry.User
+	}{
+		{"subject and email", "Bearer " + testJWT(`{"sub":"user-42","email":"jane@example.com"}`), sentry.User{ID: "user-42", Email: "jane@example.com"}},
+		{"lower case scheme", "bearer " + testJWT(`{"sub":"user-42"}`), sentry.User{ID: "user-42"}},
+		{"not a bearer token", "Basic amFuZTpodW50ZXIy", sentry.User{}},
+		{"not a JWT", "Bearer opaque-token", sentry.User{}},
+		{"invalid claims", "Bearer " + testJWT(`not json`), sentry.User{}},
+		{"missing", "", sentry.User{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			md := metadata.MD{}
+			if tt.authorization != "" {
+				md.Set("authorization", tt.authorization)
+			}
+			user := UserFromJWTClaims(context.Background(), md)
+			if user.ID != tt.expected.ID || user.Email != tt.expected.Email {
+				t.Errorf("Expected %+v, got %+v", tt.expected, user)
+			}
+		})
+	}
+}
+
+func TestUserFromTLSClientCert(t *testing.T) {
+	cert := &x509.Certificate{
+		Subject:        pkix.Name{CommonName: "billing-service", Organization: []string{"Acme"}},
+		EmailAddresses: []string{"billing@example.com"},
+	}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+	})
+
+	user := UserFromTLSClientCert(ctx, nil)
+	if user.Username != "billing-service" || user.Email != "billing@example.com" || user.ID != "CN=billing-service,O=Acme" {
+		t.Errorf("Expected user from the certificate subject, got %+v", user)
+	}
+
+	if user := UserFromTLSClientCert(context.Background(), nil); !user.IsEmpty() {
+		t.Errorf("Expected no user without peer, got %+v", user)
+	}
+}
+
+func TestUserFromALTS(t *testing.T) {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		AuthInfo: fakeALTSAuthInfo{account: "billing@project.iam.gserviceaccount.com"},
+	})
+
+	user := UserFromALTS(ctx, nil)
+	if user.ID != "billing@project.iam.gserviceaccount.com" {
+		t.Errorf("Expected user from the peer service account, got %+v", user)
+	}
+
+	tlsCtx := peer.NewContext(context.Background(), &peer.Peer{AuthInfo: credentials.TLSInfo{}})
+	if user := UserFromALTS(tlsCtx, nil); !user.IsEmpty() {
+		t.Errorf("Expected no user without ALTS, got %+v", user)
+	}
+}
+
+func TestFirstUser(t *testing.T) {
+	md := metadata.Pairs("authorization", "Bearer "+testJWT(`{"sub":"user-42"}`))
+	extractor := FirstUser(UserFromTLSClientCert, UserFromJWTClaims)
+
+	if user := extractor(context.Background(), md); user.ID != "user-42" {
+		t.Errorf("Expected the first non-empty user, got %+v", user)
+	}
+	if user := extractor(context.Background(), nil); !user.IsEmpty() {
+		t.Errorf("Expected no user, got %+v", user)
+	}
+}
+
+func TestUnaryServerInterceptor_UserExtractor(t *testing.T) {
+	hub, transport := newRecordingHub(t)
+	ctx := sentry.SetHubOnContext(context.Background(), hub)
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+testJWT(`{"sub":"user-42"}`)))
+
+	interceptor := UnaryServerInterceptor(WithUserExtractor(UserFromJWTClaims))
+	handler := &mockUnaryHandler{err: status.Error(codes.Internal, "boom")}
+	_, _ = interceptor(ctx, "request", &grpc.UnaryServerInfo{FullMethod: "/test.Service/Method"}, handler.handle)
+
+	events := transport.Errors()
+	if len(events) != 1 {
+		t.Fatalf("Expected 1 event, got %d", len(events))
+	}
+	if events[0].User.ID != "user-42" {
+		t.Errorf("Expected the extracted user on the event, got %+v", events[0].User)
+	}
+}